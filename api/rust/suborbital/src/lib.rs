@@ -201,6 +201,12 @@ pub mod cache {
     extern {
         fn cache_set(key_pointer: *const u8, key_size: i32, value_pointer: *const u8, value_size: i32, ttl: i32, ident: i32) -> i32;
         fn cache_get(key_pointer: *const u8, key_size: i32, dest_pointer: *const u8, dest_max_size: i32, ident: i32) -> i32;
+        fn cache_increment(key_pointer: *const u8, key_size: i32, delta: i32, dest_pointer: *const u8, dest_max_size: i32, ident: i32) -> i32;
+        fn cache_compare_and_swap(key_pointer: *const u8, key_size: i32, old_pointer: *const u8, old_size: i32, new_pointer: *const u8, new_size: i32, ttl: i32, ident: i32) -> i32;
+        fn cache_set_if_absent(key_pointer: *const u8, key_size: i32, value_pointer: *const u8, value_size: i32, ttl: i32, ident: i32) -> i32;
+        fn cache_expire(key_pointer: *const u8, key_size: i32, ttl: i32, ident: i32) -> i32;
+        fn cache_ttl(key_pointer: *const u8, key_size: i32, ident: i32) -> i32;
+        fn cache_keys(prefix_pointer: *const u8, prefix_size: i32, dest_pointer: *const u8, dest_max_size: i32, ident: i32) -> i32;
     }
 
     pub fn set(key: &str, val: Vec<u8>, ttl: i32) {
@@ -244,6 +250,110 @@ pub mod cache {
 
         Some(Vec::from(result))
     }
+
+    pub fn increment(key: &str, delta: i32) -> Option<i64> {
+        let mut dest_pointer: *const u8;
+        let mut result_size: i32;
+        let mut capacity: i32 = 32;
+
+        // the new value is written as a decimal string, since it may not fit in the return value
+        loop {
+            let cap = &mut capacity;
+
+            let mut dest_bytes = Vec::with_capacity(*cap as usize);
+            let dest_slice = dest_bytes.as_mut_slice();
+            dest_pointer = dest_slice.as_mut_ptr() as *const u8;
+
+            result_size = unsafe { cache_increment(key.as_ptr(), key.len() as i32, delta, dest_pointer, *cap, super::STATE.ident) };
+
+            if result_size < 0 {
+                return None;
+            } else if result_size > *cap {
+                *cap = result_size;
+            } else {
+                break;
+            }
+        }
+
+        let result: &[u8] = unsafe {
+            slice::from_raw_parts(dest_pointer, result_size as usize)
+        };
+
+        match String::from_utf8(Vec::from(result)) {
+            Ok(val) => val.parse::<i64>().ok(),
+            Err(_) => None
+        }
+    }
+
+    pub fn compare_and_swap(key: &str, old: Vec<u8>, new: Vec<u8>, ttl: i32) -> bool {
+        let result = unsafe {
+            cache_compare_and_swap(key.as_ptr(), key.len() as i32, old.as_slice().as_ptr(), old.len() as i32, new.as_slice().as_ptr(), new.len() as i32, ttl, super::STATE.ident)
+        };
+
+        result == 1
+    }
+
+    pub fn set_if_absent(key: &str, val: Vec<u8>, ttl: i32) -> bool {
+        let result = unsafe {
+            cache_set_if_absent(key.as_ptr(), key.len() as i32, val.as_slice().as_ptr(), val.len() as i32, ttl, super::STATE.ident)
+        };
+
+        result == 1
+    }
+
+    pub fn expire(key: &str, ttl: i32) -> bool {
+        let result = unsafe { cache_expire(key.as_ptr(), key.len() as i32, ttl, super::STATE.ident) };
+
+        result == 0
+    }
+
+    pub fn ttl(key: &str) -> Option<i32> {
+        let result = unsafe { cache_ttl(key.as_ptr(), key.len() as i32, super::STATE.ident) };
+
+        if result < 0 {
+            return None;
+        }
+
+        Some(result)
+    }
+
+    pub fn keys(prefix: &str) -> Vec<String> {
+        let mut dest_pointer: *const u8;
+        let mut result_size: i32;
+        let mut capacity: i32 = 1024;
+
+        loop {
+            let cap = &mut capacity;
+
+            let mut dest_bytes = Vec::with_capacity(*cap as usize);
+            let dest_slice = dest_bytes.as_mut_slice();
+            dest_pointer = dest_slice.as_mut_ptr() as *const u8;
+
+            result_size = unsafe { cache_keys(prefix.as_ptr(), prefix.len() as i32, dest_pointer, *cap, super::STATE.ident) };
+
+            if result_size < 0 {
+                return Vec::new();
+            } else if result_size > *cap {
+                *cap = result_size;
+            } else {
+                break;
+            }
+        }
+
+        if result_size == 0 {
+            return Vec::new();
+        }
+
+        let result: &[u8] = unsafe {
+            slice::from_raw_parts(dest_pointer, result_size as usize)
+        };
+
+        // keys are seperated by newlines
+        match String::from_utf8(Vec::from(result)) {
+            Ok(joined) => joined.split('\n').map(String::from).collect(),
+            Err(_) => Vec::new()
+        }
+    }
 }
 
 pub mod req {
@@ -350,6 +460,57 @@ pub mod req {
     }
 }
 
+pub mod meta {
+    use std::slice;
+    use super::util;
+
+    extern {
+        fn job_meta_get(key_pointer: *const u8, key_size: i32, dest_pointer: *const u8, dest_max_size: i32, ident: i32) -> i32;
+        fn job_deadline(ident: i32) -> i32;
+    }
+
+    pub fn deadline_remaining() -> Option<i32> {
+        let remaining = unsafe { job_deadline(super::STATE.ident) };
+
+        if remaining < 0 {
+            return None;
+        }
+
+        Some(remaining)
+    }
+
+    pub fn get(key: &str) -> Option<String> {
+        let mut dest_pointer: *const u8;
+        let mut result_size: i32;
+        let mut capacity: i32 = 256;
+
+        // make the request, and if the response size is greater than that of capacity, increase the capacity and try again
+        loop {
+            let cap = &mut capacity;
+
+            let mut dest_bytes = Vec::with_capacity(*cap as usize);
+            let dest_slice = dest_bytes.as_mut_slice();
+            dest_pointer = dest_slice.as_mut_ptr() as *const u8;
+
+            result_size = unsafe { job_meta_get(key.as_ptr(), key.len() as i32, dest_pointer, *cap, super::STATE.ident) };
+
+            if result_size < 0 {
+                return None;
+            } else if result_size > *cap {
+                *cap = result_size;
+            } else {
+                break;
+            }
+        }
+
+        let result: &[u8] = unsafe {
+            slice::from_raw_parts(dest_pointer, result_size as usize)
+        };
+
+        Some(util::to_string(Vec::from(result)))
+    }
+}
+
 pub mod log {
     extern {
         fn log_msg(pointer: *const u8, result_size: i32, level: i32, ident: i32);
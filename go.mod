@@ -4,6 +4,8 @@ go 1.15
 
 require (
 	github.com/google/uuid v1.1.3
+	github.com/gorilla/websocket v1.4.2
+	github.com/julienschmidt/httprouter v1.3.0
 	github.com/kr/pretty v0.1.0 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/suborbital/grav v0.3.0
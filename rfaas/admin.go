@@ -0,0 +1,394 @@
+package rfaas
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/reactr/bundle"
+	"github.com/suborbital/reactr/rt"
+	"github.com/suborbital/reactr/rwasm"
+	"github.com/suborbital/vektor/vk"
+)
+
+// bundleInfo describes a bundle whose Runnables are handled by the Server
+type bundleInfo struct {
+	Identifier string   `json:"identifier"`
+	AppVersion string   `json:"appVersion"`
+	JobTypes   []string `json:"jobTypes"`
+
+	// the file that the bundle was uploaded to, if it was uploaded using the admin API
+	file *bundleFile
+}
+
+// bundleFile is a bundle uploaded using the admin API. Static files are read from it on demand, so it is only
+// removed once the bundle has been replaced or unmounted and every handler of its Runnables has stopped.
+type bundleFile struct {
+	path     string
+	handlers int
+	retired  bool
+	lock     sync.Mutex
+}
+
+// acquire records a handler that reads from the file
+func (f *bundleFile) acquire() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.handlers++
+}
+
+// release records that a handler has stopped, removing the file if it was the last and the file has been retired
+func (f *bundleFile) release() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.handlers--
+	f.removeIfUnused()
+}
+
+// retire marks the file as no longer being needed for new handlers, removing it if no handlers are using it
+func (f *bundleFile) retire() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.retired = true
+	f.removeIfUnused()
+}
+
+// removeIfUnused removes the file if it has been retired and no handlers are using it. THE CALLER MUST LOCK.
+func (f *bundleFile) removeIfUnused() {
+	if f.retired && f.handlers == 0 {
+		os.Remove(f.path)
+	}
+}
+
+// bundleHandler handles a bundle's Runnables for a Tenant, holding the bundle's file (if any) until each of its handlers has stopped
+type bundleHandler struct {
+	tenant *Tenant
+	file   *bundleFile
+}
+
+// Handle implements rwasm.Handler
+func (b bundleHandler) Handle(jobType string, runner rt.Runnable, options ...rt.Option) rt.JobFunc {
+	if b.file != nil {
+		b.file.acquire()
+		options = append(options, rt.OnStopped(b.file.release))
+	}
+
+	return b.tenant.Handle(jobType, runner, options...)
+}
+
+// adminRoutes returns the routes of the admin API, which are only available to admin clients
+func (s *Server) adminRoutes() *vk.RouteGroup {
+	admin := vk.Group("/admin").Before(s.limitMiddleware(), s.authMiddleware(), s.adminMiddleware())
+
+	admin.GET("/handlers", s.adminHandlersHandler())
+	admin.GET("/bundles", s.adminBundlesHandler())
+	admin.POST("/bundles", s.adminUploadBundleHandler())
+	admin.DELETE("/bundles/:identifier", s.adminDeleteBundleHandler())
+	admin.GET("/results", s.adminResultsHandler())
+	admin.DELETE("/results/:id", s.adminCancelResultHandler())
+	admin.GET("/schedules", s.adminSchedulesHandler())
+
+	return admin
+}
+
+// adminMiddleware ensures the client is an admin. It must run after authMiddleware.
+func (s *Server) adminMiddleware() vk.Middleware {
+	return func(r *http.Request, ctx *vk.Ctx) error {
+		opts := s.getOptions()
+
+		if len(opts.Authenticators) == 0 {
+			return vk.E(http.StatusForbidden, "the admin API requires authentication to be configured")
+		}
+
+		for _, id := range opts.AdminClients {
+			if id == clientID(ctx) {
+				return nil
+			}
+		}
+
+		return vk.E(http.StatusForbidden, "client is not an admin")
+	}
+}
+
+// adminTenant returns the Tenant named by the request's tenant query parameter, or the Server's own if there is none
+func (s *Server) adminTenant(r *http.Request) (*Tenant, error) {
+	name := r.URL.Query().Get("tenant")
+	if name == "" {
+		return s.root, nil
+	}
+
+	t := s.Tenant(name)
+	if t == nil {
+		return nil, vk.E(http.StatusNotFound, fmt.Sprintf("tenant %s not found", name))
+	}
+
+	return t, nil
+}
+
+func (s *Server) adminHandlersHandler() vk.HandlerFunc {
+	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
+		t, err := s.adminTenant(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return t.Handlers(), nil
+	}
+}
+
+func (s *Server) adminSchedulesHandler() vk.HandlerFunc {
+	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
+		t, err := s.adminTenant(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return t.Schedules(), nil
+	}
+}
+
+func (s *Server) adminBundlesHandler() vk.HandlerFunc {
+	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
+		t, err := s.adminTenant(r)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Lock()
+		defer s.Unlock()
+
+		bundles := make([]bundleInfo, 0, len(t.bundles))
+		for _, b := range t.bundles {
+			bundles = append(bundles, *b)
+		}
+
+		sort.Slice(bundles, func(i, j int) bool {
+			return bundles[i].Identifier < bundles[j].Identifier
+		})
+
+		return bundles, nil
+	}
+}
+
+// adminUploadBundleHandler handles the Runnables of a .wasm.zip bundle sent as the request body,
+// replacing those of any bundle with the same identifier
+func (s *Server) adminUploadBundleHandler() vk.HandlerFunc {
+	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
+		t, err := s.adminTenant(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// the bundle must stay on disk while it is mounted, since static files are read from it on demand
+		file, err := ioutil.TempFile("", "rfaas-*.wasm.zip")
+		if err != nil {
+			return nil, vk.E(http.StatusInternalServerError, "failed to create bundle file")
+		}
+
+		_, copyErr := io.Copy(file, r.Body)
+		file.Close()
+		r.Body.Close()
+
+		if copyErr == errBodyTooLarge {
+			os.Remove(file.Name())
+			return nil, vk.E(http.StatusRequestEntityTooLarge, "bundle is too large")
+		} else if copyErr != nil {
+			os.Remove(file.Name())
+			return nil, vk.E(http.StatusInternalServerError, "failed to read bundle")
+		}
+
+		b, err := bundle.Read(file.Name())
+		if err != nil {
+			os.Remove(file.Name())
+			return nil, vk.E(http.StatusBadRequest, errors.Wrap(err, "failed to Read bundle").Error())
+		}
+
+		upload := &bundleFile{path: file.Name()}
+
+		info, err := s.handleBundleRunnables(t, b, upload)
+		if err != nil {
+			// any of the bundle's Runnables that were handled before the error keep the file until they stop
+			upload.retire()
+			return nil, vk.E(http.StatusBadRequest, err.Error())
+		}
+
+		ctx.Log.Info("mounted bundle", info.Identifier, info.AppVersion)
+
+		return vk.R(http.StatusCreated, info), nil
+	}
+}
+
+func (s *Server) adminDeleteBundleHandler() vk.HandlerFunc {
+	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
+		t, err := s.adminTenant(r)
+		if err != nil {
+			return nil, err
+		}
+
+		identifier := ctx.Params.ByName("identifier")
+
+		s.bundleLock.Lock()
+		defer s.bundleLock.Unlock()
+
+		s.Lock()
+		info, exists := t.bundles[identifier]
+		delete(t.bundles, identifier)
+		owned := t.disownJobTypes(identifier, info)
+		s.Unlock()
+
+		if !exists {
+			return nil, vk.E(http.StatusNotFound, fmt.Sprintf("bundle %s is not mounted", identifier))
+		}
+
+		// job types that another bundle has since handled belong to that bundle, and must be left in place
+		s.unhandleJobTypes(t, owned)
+
+		if info.file != nil {
+			info.file.retire()
+		}
+
+		ctx.Log.Info("unmounted bundle", identifier)
+
+		return info, nil
+	}
+}
+
+func (s *Server) adminResultsHandler() vk.HandlerFunc {
+	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
+		t, err := s.adminTenant(r)
+		if err != nil {
+			return nil, err
+		}
+
+		pending := t.results.pending()
+
+		statuses := make([]statusResponse, len(pending))
+		for i, record := range pending {
+			statuses[i] = record.statusResponse()
+		}
+
+		return statuses, nil
+	}
+}
+
+// adminCancelResultHandler cancels a pending result. Runnables can't be interrupted, so the job
+// will run to completion, but its result is discarded and anyone waiting for it is released.
+func (s *Server) adminCancelResultHandler() vk.HandlerFunc {
+	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
+		t, err := s.adminTenant(r)
+		if err != nil {
+			return nil, err
+		}
+
+		id := ctx.Params.ByName("id")
+
+		record := t.results.get(id)
+		if record == nil {
+			return nil, vk.E(http.StatusNotFound, fmt.Sprintf("result with ID %s not found", id))
+		}
+
+		if !record.cancel() {
+			return nil, vk.E(http.StatusConflict, fmt.Sprintf("result with ID %s has already completed", id))
+		}
+
+		t.results.publish(record)
+
+		return record.statusResponse(), nil
+	}
+}
+
+// handleBundleRunnables handles each of the bundle's Runnables for the Tenant, replacing any of its bundles that has the same identifier.
+// The file that the bundle was uploaded to is nil if it wasn't uploaded using the admin API.
+func (s *Server) handleBundleRunnables(t *Tenant, b *bundle.Bundle, file *bundleFile) (*bundleInfo, error) {
+	s.bundleLock.Lock()
+	defer s.bundleLock.Unlock()
+
+	if err := rwasm.HandleBundle(bundleHandler{tenant: t, file: file}, b); err != nil {
+		return nil, errors.Wrap(err, "failed to HandleBundle")
+	}
+
+	info := &bundleInfo{
+		Identifier: b.Directive.Identifier,
+		AppVersion: b.Directive.AppVersion,
+		JobTypes:   []string{},
+		file:       file,
+	}
+
+	// rwasm.HandleBundle handles each Runnable using both its name and its FQFN
+	for _, r := range b.Runnables {
+		jobName := strings.TrimSuffix(r.Name, ".wasm")
+
+		fqfn, err := b.Directive.FQFN(jobName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to FQFN for %s", jobName)
+		}
+
+		info.JobTypes = append(info.JobTypes, jobName, fqfn)
+	}
+
+	s.Lock()
+	previous := t.bundles[info.Identifier]
+	t.bundles[info.Identifier] = info
+
+	current := map[string]bool{}
+	for _, jobType := range info.JobTypes {
+		current[jobType] = true
+	}
+
+	// the new bundle's Runnables have replaced the previous bundle's, but any it no longer contains must be removed
+	removed := []string{}
+	for _, jobType := range t.disownJobTypes(info.Identifier, previous) {
+		if !current[jobType] {
+			removed = append(removed, jobType)
+		}
+	}
+
+	for _, jobType := range info.JobTypes {
+		t.bundleOwners[jobType] = info.Identifier
+	}
+	s.Unlock()
+
+	s.unhandleJobTypes(t, removed)
+
+	// the previous bundle's handlers have all been replaced or unhandled, so its file can go once they have stopped
+	if previous != nil && previous.file != nil && previous.file != file {
+		previous.file.retire()
+	}
+
+	return info, nil
+}
+
+// disownJobTypes removes the bundle with the given identifier as the owner of each of info's job types,
+// returning those it still owned. THE CALLER MUST LOCK.
+func (t *Tenant) disownJobTypes(identifier string, info *bundleInfo) []string {
+	owned := []string{}
+	if info == nil {
+		return owned
+	}
+
+	for _, jobType := range info.JobTypes {
+		if t.bundleOwners[jobType] == identifier {
+			delete(t.bundleOwners, jobType)
+			owned = append(owned, jobType)
+		}
+	}
+
+	return owned
+}
+
+func (s *Server) unhandleJobTypes(t *Tenant, jobTypes []string) {
+	for _, jobType := range jobTypes {
+		if err := t.Unhandle(jobType); err != nil {
+			s.log.Error(errors.Wrapf(err, "failed to Unhandle %s", jobType))
+		}
+	}
+}
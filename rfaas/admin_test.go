@@ -0,0 +1,414 @@
+package rfaas
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/suborbital/reactr/bundle"
+	"github.com/suborbital/reactr/directive"
+	"github.com/suborbital/reactr/rt"
+)
+
+func startAdminTestServer(t *testing.T) string {
+	return startTestServer(t, func(s *Server) {
+		s.Configure(
+			UseAuthenticators(NewAPIKeyAuthenticator(map[string]string{"admin-key": "admin", "user-key": "user"})),
+			UseAdminClients("admin"),
+		)
+	})
+}
+
+// testBundle writes a bundle containing a single Runnable to a temporary directory and returns its contents
+func testBundle(t *testing.T) []byte {
+	return testBundleWithIdentifier(t, "com.suborbital.admin")
+}
+
+// testBundleWithIdentifier is testBundle for a bundle with the given identifier
+func testBundleWithIdentifier(t *testing.T, identifier string) []byte {
+	dir, err := ioutil.TempDir("", "rfaas-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	modulePath := filepath.Join(dir, "hello.wasm")
+
+	// the module is never run, so its contents don't matter
+	if err := ioutil.WriteFile(modulePath, []byte("not really wasm"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := os.Open(modulePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer module.Close()
+
+	d := &directive.Directive{
+		Identifier:  identifier,
+		AppVersion:  "v0.1.0",
+		AtmoVersion: "v0.0.6",
+		Runnables:   []directive.Runnable{{Name: "hello", Namespace: "default"}},
+	}
+
+	bundlePath := filepath.Join(dir, "runnables.wasm.zip")
+
+	if err := bundle.Write(d, []os.File{*module}, nil, bundlePath); err != nil {
+		t.Fatal(err)
+	}
+
+	bundleBytes, err := ioutil.ReadFile(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bundleBytes
+}
+
+func TestAdminAuth(t *testing.T) {
+	open := startTestServer(t, nil)
+
+	if status, _ := doRequest(t, http.MethodGet, open+"/admin/handlers", nil); status != http.StatusForbidden {
+		t.Errorf("expected 403 without authentication configured, got %d", status)
+	}
+
+	baseURL := startAdminTestServer(t)
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/admin/handlers", nil); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 without credentials, got %d", status)
+	}
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/admin/handlers", nil, withHeader(HeaderAPIKey, "user-key")); status != http.StatusForbidden {
+		t.Errorf("expected 403 for non-admin, got %d", status)
+	}
+
+	status, body := doRequest(t, http.MethodGet, baseURL+"/admin/handlers", nil, withHeader(HeaderAPIKey, "admin-key"))
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, string(body))
+	}
+
+	handlers := []rt.HandlerInfo{}
+	if err := json.Unmarshal(body, &handlers); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(handlers) != 1 || handlers[0].JobType != "echo" {
+		t.Errorf("expected echo handler, got %+v", handlers)
+	}
+}
+
+func TestAdminBundles(t *testing.T) {
+	baseURL := startAdminTestServer(t)
+	admin := withHeader(HeaderAPIKey, "admin-key")
+
+	status, body := doRequest(t, http.MethodPost, baseURL+"/admin/bundles", testBundle(t), admin)
+	if status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", status, string(body))
+	}
+
+	hasHandler := func(jobType string) bool {
+		_, body := doRequest(t, http.MethodGet, baseURL+"/admin/handlers", nil, admin)
+
+		handlers := []rt.HandlerInfo{}
+		if err := json.Unmarshal(body, &handlers); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, h := range handlers {
+			if h.JobType == jobType {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if !hasHandler("hello") || !hasHandler("default#hello@v0.1.0") {
+		t.Error("expected bundle's Runnable to be handled")
+	}
+
+	_, body = doRequest(t, http.MethodGet, baseURL+"/admin/bundles", nil, admin)
+
+	bundles := []bundleInfo{}
+	if err := json.Unmarshal(body, &bundles); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bundles) != 1 || bundles[0].Identifier != "com.suborbital.admin" {
+		t.Errorf("unexpected bundles %+v", bundles)
+	}
+
+	if status, _ := doRequest(t, http.MethodDelete, baseURL+"/admin/bundles/com.suborbital.admin", nil, admin); status != http.StatusOK {
+		t.Errorf("expected 200, got %d", status)
+	}
+
+	if hasHandler("hello") {
+		t.Error("expected bundle's Runnable to be unhandled")
+	}
+
+	if status, _ := doRequest(t, http.MethodDelete, baseURL+"/admin/bundles/com.suborbital.admin", nil, admin); status != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", status)
+	}
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/admin/bundles", []byte("not a bundle"), admin); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid bundle, got %d", status)
+	}
+}
+
+func TestAdminBundleOwnership(t *testing.T) {
+	var server *Server
+
+	baseURL := startTestServer(t, func(s *Server) {
+		server = s
+
+		s.Configure(
+			UseAuthenticators(NewAPIKeyAuthenticator(map[string]string{"admin-key": "admin"})),
+			UseAdminClients("admin"),
+		)
+	})
+
+	admin := withHeader(HeaderAPIKey, "admin-key")
+
+	hasHandler := func(jobType string) bool {
+		for _, h := range server.Handlers() {
+			if h.JobType == jobType {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, identifier := range []string{"com.suborbital.one", "com.suborbital.two"} {
+		if status, body := doRequest(t, http.MethodPost, baseURL+"/admin/bundles", testBundleWithIdentifier(t, identifier), admin); status != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", status, string(body))
+		}
+	}
+
+	server.Lock()
+	onePath := server.root.bundles["com.suborbital.one"].file.path
+	server.Unlock()
+
+	// the second bundle's Runnable has replaced the first's, so unmounting the first must leave it in place
+	if status, _ := doRequest(t, http.MethodDelete, baseURL+"/admin/bundles/com.suborbital.one", nil, admin); status != http.StatusOK {
+		t.Errorf("expected 200, got %d", status)
+	}
+
+	if !hasHandler("hello") {
+		t.Error("expected the second bundle's Runnable to still be handled")
+	}
+
+	// the first bundle's file is removed once its replaced handlers have stopped, which waits for their starts to give up
+	removed := false
+	for i := 0; i < 100 && !removed; i++ {
+		if _, err := os.Stat(onePath); os.IsNotExist(err) {
+			removed = true
+		} else {
+			time.Sleep(time.Millisecond * 100)
+		}
+	}
+
+	if !removed {
+		t.Error("expected the first bundle's file to be removed")
+	}
+
+	if status, _ := doRequest(t, http.MethodDelete, baseURL+"/admin/bundles/com.suborbital.two", nil, admin); status != http.StatusOK {
+		t.Errorf("expected 200, got %d", status)
+	}
+
+	if hasHandler("hello") {
+		t.Error("expected the second bundle's Runnable to be unhandled")
+	}
+}
+
+func TestBundleFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "rfaas-*.wasm.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file.Close()
+
+	f := &bundleFile{path: file.Name()}
+	f.acquire()
+	f.retire()
+
+	// the file must stay while a handler is still using it
+	if _, err := os.Stat(f.path); err != nil {
+		t.Errorf("expected file to exist while in use, got %v", err)
+	}
+
+	f.release()
+
+	if _, err := os.Stat(f.path); !os.IsNotExist(err) {
+		t.Errorf("expected file to be removed, got %v", err)
+	}
+}
+
+func TestAdminResults(t *testing.T) {
+	baseURL := startAdminTestServer(t)
+	admin := withHeader(HeaderAPIKey, "admin-key")
+
+	status, body := doRequest(t, http.MethodPost, baseURL+"/do/echo", []byte("slow"), admin)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, string(body))
+	}
+
+	resp := doResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	_, body = doRequest(t, http.MethodGet, baseURL+"/admin/results", nil, admin)
+
+	pending := []statusResponse{}
+	if err := json.Unmarshal(body, &pending); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pending) != 1 || pending[0].ID != resp.ResultID {
+		t.Fatalf("expected pending result, got %+v", pending)
+	}
+
+	if status, _ := doRequest(t, http.MethodDelete, baseURL+"/admin/results/"+resp.ResultID, nil, admin); status != http.StatusOK {
+		t.Errorf("expected 200, got %d", status)
+	}
+
+	status, body = doRequest(t, http.MethodGet, baseURL+"/status/"+resp.ResultID, nil, admin)
+
+	result := statusResponse{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if status != http.StatusOK || result.Status != ResultStatusCanceled {
+		t.Errorf("expected canceled status, got %d %+v", status, result)
+	}
+
+	// once the job completes, the cancellation must stick
+	time.Sleep(time.Millisecond * 600)
+
+	if status, _ := doRequest(t, http.MethodDelete, baseURL+"/admin/results/"+resp.ResultID, nil, admin); status != http.StatusConflict {
+		t.Errorf("expected 409, got %d", status)
+	}
+
+	_, body = doRequest(t, http.MethodGet, baseURL+"/status/"+resp.ResultID, nil, admin)
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Status != ResultStatusCanceled {
+		t.Errorf("expected canceled status, got %+v", result)
+	}
+}
+
+func TestAdminSchedules(t *testing.T) {
+	var server *Server
+
+	baseURL := startTestServer(t, func(s *Server) {
+		server = s
+
+		s.Configure(
+			UseAuthenticators(NewAPIKeyAuthenticator(map[string]string{"admin-key": "admin"})),
+			UseAdminClients("admin"),
+		)
+	})
+
+	server.Schedule(rt.EveryDuration(time.Hour, func() rt.Job {
+		return rt.NewJob("echo", "scheduled")
+	}))
+
+	_, body := doRequest(t, http.MethodGet, baseURL+"/admin/schedules", nil, withHeader(HeaderAPIKey, "admin-key"))
+
+	schedules := []rt.ScheduleInfo{}
+	if err := json.Unmarshal(body, &schedules); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(schedules) != 1 || schedules[0].Kind != rt.ScheduleKindEvery || schedules[0].Interval != time.Hour {
+		t.Errorf("unexpected schedules %+v", schedules)
+	}
+}
+
+func TestAdminTenant(t *testing.T) {
+	baseURL := startTestServer(t, func(s *Server) {
+		s.Configure(
+			UseAuthenticators(NewAPIKeyAuthenticator(map[string]string{"admin-key": "admin", "key-a": "a"})),
+			UseAdminClients("admin"),
+		)
+
+		alpha := addTenant(t, s, "alpha", UseTenantClients("a"))
+		alpha.Handle("count", &counter{})
+	})
+
+	admin := withHeader(HeaderAPIKey, "admin-key")
+
+	_, body := doRequest(t, http.MethodGet, baseURL+"/admin/handlers?tenant=alpha", nil, admin)
+
+	handlers := []rt.HandlerInfo{}
+	if err := json.Unmarshal(body, &handlers); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(handlers) != 1 || handlers[0].JobType != "count" {
+		t.Errorf("expected tenant's count handler, got %+v", handlers)
+	}
+
+	// bundles uploaded for a tenant are handled by the tenant rather than the server
+	if status, body := doRequest(t, http.MethodPost, baseURL+"/admin/bundles?tenant=alpha", testBundle(t), admin); status != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", status, string(body))
+	}
+
+	_, body = doRequest(t, http.MethodGet, baseURL+"/admin/handlers", nil, admin)
+	if err := json.Unmarshal(body, &handlers); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(handlers) != 1 || handlers[0].JobType != "echo" {
+		t.Errorf("expected only the server's echo handler, got %+v", handlers)
+	}
+
+	_, body = doRequest(t, http.MethodGet, baseURL+"/admin/bundles?tenant=alpha", nil, admin)
+
+	bundles := []bundleInfo{}
+	if err := json.Unmarshal(body, &bundles); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bundles) != 1 || bundles[0].Identifier != "com.suborbital.admin" {
+		t.Errorf("unexpected tenant bundles %+v", bundles)
+	}
+
+	// the tenant's pending results are listed and canceled separately from the server's
+	id := scheduleForID(t, baseURL+"/t/alpha", "count", "", withHeader(HeaderAPIKey, "key-a"))
+
+	_, body = doRequest(t, http.MethodGet, baseURL+"/admin/results?tenant=alpha", nil, admin)
+
+	pending := []statusResponse{}
+	if err := json.Unmarshal(body, &pending); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pending) != 1 || pending[0].ID != id {
+		t.Errorf("expected tenant's pending result, got %+v", pending)
+	}
+
+	if status, _ := doRequest(t, http.MethodDelete, baseURL+"/admin/results/"+id, nil, admin); status != http.StatusNotFound {
+		t.Errorf("expected tenant's result not to be found in the server's, got %d", status)
+	}
+
+	if status, _ := doRequest(t, http.MethodDelete, baseURL+"/admin/results/"+id+"?tenant=alpha", nil, admin); status != http.StatusOK {
+		t.Errorf("expected 200 canceling tenant's result, got %d", status)
+	}
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/admin/handlers?tenant=gamma", nil, admin); status != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown tenant, got %d", status)
+	}
+}
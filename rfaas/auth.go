@@ -0,0 +1,233 @@
+package rfaas
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/vektor/vk"
+)
+
+// HeaderAPIKey and others are the HTTP headers used to authenticate requests
+const (
+	HeaderAPIKey    = "X-Reactr-API-Key"
+	HeaderClientID  = "X-Reactr-Client"
+	HeaderTimestamp = "X-Reactr-Timestamp"
+	HeaderSignature = "X-Reactr-Signature"
+)
+
+// the maximum difference between the timestamp of an HMAC-signed request and the server's clock
+const maxSignatureSkew = time.Minute * 5
+
+// the vk.Ctx key that holds the authenticated client's ID
+const ctxKeyClientID = "rfaas.clientID"
+
+// ErrInvalidCredentials is returned by an Authenticator when a request includes credentials that are not valid
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator identifies the client making a request. If the request does not include the kind of
+// credentials the Authenticator handles, it should return an empty client ID and no error so that the
+// next Authenticator can be tried. If the credentials are present but not valid, it should return an error.
+type Authenticator interface {
+	Authenticate(r *http.Request, body []byte) (string, error)
+}
+
+// Authorizer decides if a client is allowed to run jobs of a particular type
+type Authorizer interface {
+	Authorize(clientID, jobType string) bool
+}
+
+// Policy is an Authorizer that maps client IDs to the job types they are allowed to run.
+// A job type of "*" allows the client to run any job type, and a client ID of "*" applies to every client.
+type Policy map[string][]string
+
+// Authorize returns true if the policy allows the client to run the job type
+func (p Policy) Authorize(clientID, jobType string) bool {
+	for _, id := range []string{clientID, "*"} {
+		for _, allowed := range p[id] {
+			if allowed == jobType || allowed == "*" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// APIKeyAuthenticator authenticates requests that include a static API key in the X-Reactr-API-Key header
+type APIKeyAuthenticator struct {
+	keys map[string]string
+}
+
+// NewAPIKeyAuthenticator creates an APIKeyAuthenticator from a map of API keys to the client IDs they belong to
+func NewAPIKeyAuthenticator(keys map[string]string) *APIKeyAuthenticator {
+	a := &APIKeyAuthenticator{
+		keys: keys,
+	}
+
+	return a
+}
+
+// Authenticate returns the client ID for the request's API key
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request, _ []byte) (string, error) {
+	key := r.Header.Get(HeaderAPIKey)
+	if key == "" {
+		return "", nil
+	}
+
+	clientID := ""
+
+	// compare against every key in constant time so that the timing does not reveal anything about the keys
+	for k, id := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			clientID = id
+		}
+	}
+
+	if clientID == "" {
+		return "", ErrInvalidCredentials
+	}
+
+	return clientID, nil
+}
+
+// HMACAuthenticator authenticates requests signed with a secret shared with each client (see SignRequest)
+type HMACAuthenticator struct {
+	secrets map[string][]byte
+}
+
+// NewHMACAuthenticator creates an HMACAuthenticator from a map of client IDs to their secrets
+func NewHMACAuthenticator(secrets map[string][]byte) *HMACAuthenticator {
+	h := &HMACAuthenticator{
+		secrets: secrets,
+	}
+
+	return h
+}
+
+// Authenticate verifies the request's signature and returns the client ID that signed it
+func (h *HMACAuthenticator) Authenticate(r *http.Request, body []byte) (string, error) {
+	signature := r.Header.Get(HeaderSignature)
+	if signature == "" {
+		return "", nil
+	}
+
+	clientID := r.Header.Get(HeaderClientID)
+
+	secret, exists := h.secrets[clientID]
+	if !exists {
+		return "", errors.Wrapf(ErrInvalidCredentials, "unknown client %q", clientID)
+	}
+
+	timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return "", errors.Wrap(ErrInvalidCredentials, "invalid timestamp")
+	}
+
+	// reject old (or future) requests so that captured requests can't be replayed later
+	if skew := time.Since(time.Unix(timestamp, 0)); skew > maxSignatureSkew || skew < -maxSignatureSkew {
+		return "", errors.Wrap(ErrInvalidCredentials, "timestamp is outside of the allowed window")
+	}
+
+	expected := requestSignature(secret, r.Header.Get(HeaderTimestamp), r.Method, r.URL.RequestURI(), body)
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", errors.Wrap(ErrInvalidCredentials, "signature mismatch")
+	}
+
+	return clientID, nil
+}
+
+// SignRequest adds the headers needed for an HMACAuthenticator to authenticate the request
+func SignRequest(r *http.Request, clientID string, secret []byte, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	r.Header.Set(HeaderClientID, clientID)
+	r.Header.Set(HeaderTimestamp, timestamp)
+	r.Header.Set(HeaderSignature, requestSignature(secret, timestamp, r.Method, r.URL.RequestURI(), body))
+}
+
+// requestSignature is the hex-encoded HMAC-SHA256 of the timestamp, method, URI, and body, separated by newlines
+func requestSignature(secret []byte, timestamp, method, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(fmt.Sprintf("%s\n%s\n%s\n", timestamp, method, uri)))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// authMiddleware authenticates each request, and authorizes the job type for requests that schedule jobs
+func (s *Server) authMiddleware() vk.Middleware {
+	return func(r *http.Request, ctx *vk.Ctx) error {
+		opts := s.getOptions()
+
+		if len(opts.Authenticators) == 0 {
+			return nil
+		}
+
+		body, err := readBody(r)
+		if err != nil {
+			return err
+		}
+
+		// put the body back so that the handler can read it
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		clientID := ""
+
+		for _, a := range opts.Authenticators {
+			id, err := a.Authenticate(r, body)
+			if err != nil {
+				ctx.Log.Error(errors.Wrap(err, "failed to Authenticate"))
+				return vk.E(http.StatusUnauthorized, "invalid credentials")
+			}
+
+			if id != "" {
+				clientID = id
+				break
+			}
+		}
+
+		if clientID == "" {
+			return vk.E(http.StatusUnauthorized, "missing credentials")
+		}
+
+		ctx.Set(ctxKeyClientID, clientID)
+
+		if jobType := ctx.Params.ByName("jobtype"); jobType != "" {
+			return s.authorize(ctx, jobType)
+		}
+
+		return nil
+	}
+}
+
+// authorize returns an error if the request's client is not allowed to run jobType
+func (s *Server) authorize(ctx *vk.Ctx, jobType string) error {
+	opts := s.getOptions()
+
+	if len(opts.Authenticators) == 0 || opts.Authorizer == nil {
+		return nil
+	}
+
+	if !opts.Authorizer.Authorize(clientID(ctx), jobType) {
+		return vk.E(http.StatusForbidden, fmt.Sprintf("client is not allowed to run %s", jobType))
+	}
+
+	return nil
+}
+
+// clientID returns the ID of the client that made the request, or an empty string if authentication is disabled
+func clientID(ctx *vk.Ctx) string {
+	id, _ := ctx.Get(ctxKeyClientID).(string)
+
+	return id
+}
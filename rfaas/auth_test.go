@@ -0,0 +1,165 @@
+package rfaas
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func withHeader(key, val string) func(*http.Request) {
+	return func(r *http.Request) {
+		r.Header.Set(key, val)
+	}
+}
+
+func makeJWT(t *testing.T, header, claims map[string]interface{}, sign func(signed string) []byte) string {
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(signed))
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	baseURL := startTestServer(t, func(s *Server) {
+		s.Configure(
+			UseAuthenticators(NewAPIKeyAuthenticator(map[string]string{"key-a": "a", "key-b": "b"})),
+			UseAuthorizer(Policy{"a": {"echo"}}),
+		)
+	})
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/do/echo", []byte("hi")); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with no credentials, got %d", status)
+	}
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/do/echo", []byte("hi"), withHeader(HeaderAPIKey, "key-c")); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with invalid key, got %d", status)
+	}
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/do/echo", []byte("hi"), withHeader(HeaderAPIKey, "key-b")); status != http.StatusForbidden {
+		t.Errorf("expected 403 for unauthorized job type, got %d", status)
+	}
+
+	status, body := doRequest(t, http.MethodPost, baseURL+"/do/echo", []byte("hi"), withHeader(HeaderAPIKey, "key-a"))
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, string(body))
+	}
+
+	resp := doResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/then/"+resp.ResultID, nil, withHeader(HeaderAPIKey, "key-b")); status != http.StatusForbidden {
+		t.Errorf("expected 403 for another client's result, got %d", status)
+	}
+
+	status, body = doRequest(t, http.MethodGet, baseURL+"/then/"+resp.ResultID, nil, withHeader(HeaderAPIKey, "key-a"))
+	if status != http.StatusOK || string(body) != "hi" {
+		t.Errorf("expected 200 'hi', got %d %q", status, string(body))
+	}
+}
+
+func TestHMACAuth(t *testing.T) {
+	secret := []byte("shh")
+
+	baseURL := startTestServer(t, func(s *Server) {
+		s.Configure(UseAuthenticators(NewHMACAuthenticator(map[string][]byte{"a": secret})))
+	})
+
+	sign := func(secret []byte) func(*http.Request) {
+		return func(r *http.Request) {
+			SignRequest(r, "a", secret, []byte("hi"))
+		}
+	}
+
+	status, body := doRequest(t, http.MethodPost, baseURL+"/do/echo?then=true", []byte("hi"), sign(secret))
+	if status != http.StatusOK || string(body) != "hi" {
+		t.Errorf("expected 200 'hi', got %d %q", status, string(body))
+	}
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/do/echo?then=true", []byte("hi"), sign([]byte("wrong"))); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong secret, got %d", status)
+	}
+
+	// the signature covers the body, so a modified body must be rejected
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/do/echo?then=true", []byte("bye"), sign(secret)); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with modified body, got %d", status)
+	}
+
+	stale := func(r *http.Request) {
+		sign(secret)(r)
+		r.Header.Set(HeaderTimestamp, "1000")
+	}
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/do/echo?then=true", []byte("hi"), stale); status != http.StatusUnauthorized {
+		t.Errorf("expected 401 with stale timestamp, got %d", status)
+	}
+}
+
+func TestJWTAuth(t *testing.T) {
+	hsKey := []byte("jwt-secret")
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := NewJWTAuthenticator(map[string]interface{}{"hs": hsKey, "ec": &ecKey.PublicKey})
+	auth.Issuer = "test"
+
+	baseURL := startTestServer(t, func(s *Server) {
+		s.Configure(UseAuthenticators(auth))
+	})
+
+	signHS := func(signed string) []byte {
+		mac := hmac.New(sha256.New, hsKey)
+		mac.Write([]byte(signed))
+		return mac.Sum(nil)
+	}
+
+	signES := func(signed string) []byte {
+		hash := sha256.Sum256([]byte(signed))
+
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+
+		return sig
+	}
+
+	exp := time.Now().Add(time.Minute).Unix()
+
+	cases := []struct {
+		name     string
+		token    string
+		expected int
+	}{
+		{"HS256", makeJWT(t, map[string]interface{}{"alg": "HS256", "kid": "hs"}, map[string]interface{}{"sub": "a", "iss": "test", "exp": exp}, signHS), http.StatusOK},
+		{"ES256", makeJWT(t, map[string]interface{}{"alg": "ES256", "kid": "ec"}, map[string]interface{}{"sub": "a", "iss": "test", "exp": exp}, signES), http.StatusOK},
+		{"expired", makeJWT(t, map[string]interface{}{"alg": "HS256", "kid": "hs"}, map[string]interface{}{"sub": "a", "iss": "test", "exp": time.Now().Add(-time.Minute).Unix()}, signHS), http.StatusUnauthorized},
+		{"wrong issuer", makeJWT(t, map[string]interface{}{"alg": "HS256", "kid": "hs"}, map[string]interface{}{"sub": "a", "iss": "other", "exp": exp}, signHS), http.StatusUnauthorized},
+		{"wrong alg for key", makeJWT(t, map[string]interface{}{"alg": "HS256", "kid": "ec"}, map[string]interface{}{"sub": "a", "iss": "test", "exp": exp}, signHS), http.StatusUnauthorized},
+		{"none", makeJWT(t, map[string]interface{}{"alg": "none", "kid": "hs"}, map[string]interface{}{"sub": "a", "iss": "test", "exp": exp}, func(string) []byte { return nil }), http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		status, body := doRequest(t, http.MethodPost, baseURL+"/do/echo?then=true", []byte("hi"), withHeader("Authorization", "Bearer "+c.token))
+		if status != c.expected {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.expected, status, string(body))
+		}
+	}
+}
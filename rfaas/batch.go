@@ -0,0 +1,184 @@
+package rfaas
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/reactr/rt"
+	"github.com/suborbital/vektor/vk"
+)
+
+// batchItem is a single job in a batch request. Binary data, which can't be sent as a JSON string, is sent as DataBase64.
+type batchItem struct {
+	JobType    string          `json:"jobType"`
+	Data       json.RawMessage `json:"data"`
+	DataBase64 []byte          `json:"dataBase64,omitempty"`
+}
+
+// batchResponse contains the result IDs of a batch's jobs, in the order they were submitted
+type batchResponse struct {
+	ResultIDs []string `json:"resultIds"`
+}
+
+// batchResult is the result of a single job in a batch
+type batchResult struct {
+	ResultID string `json:"resultId"`
+	Status   string `json:"status"`
+	Result   []byte `json:"result,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (s *Server) batchHandler() vk.HandlerFunc {
+	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
+		body, err := readBody(r)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := parseBatch(body)
+		if err != nil {
+			return nil, vk.E(http.StatusBadRequest, errors.Wrap(err, "failed to parse batch").Error())
+		}
+
+		opts := s.getOptions()
+
+		if len(items) == 0 {
+			return nil, vk.E(http.StatusBadRequest, "batch is empty")
+		} else if opts.MaxBatchSize > 0 && len(items) > opts.MaxBatchSize {
+			return nil, vk.E(http.StatusRequestEntityTooLarge, fmt.Sprintf("batch contains more than %d jobs", opts.MaxBatchSize))
+		}
+
+		// check every item before scheduling any of them so that a batch is either accepted or rejected as a whole
+		for i, item := range items {
+			if item.JobType == "" {
+				return nil, vk.E(http.StatusBadRequest, fmt.Sprintf("item %d is missing jobType", i))
+			}
+
+			if item.DataBase64 != nil && len(item.Data) != 0 && string(item.Data) != "null" {
+				return nil, vk.E(http.StatusBadRequest, fmt.Sprintf("item %d has both data and dataBase64", i))
+			}
+
+			if err := s.authorize(ctx, item.JobType); err != nil {
+				return nil, err
+			}
+		}
+
+		tenant := s.tenantFor(ctx)
+
+		group := rt.NewGroup()
+		results := make([]*rt.Result, len(items))
+
+		for i, item := range items {
+			job := s.jobWithRequestMeta(ctx, rt.NewJob(item.JobType, item.bytes()), r.Header)
+
+			results[i] = tenant.Do(job)
+			group.Add(results[i])
+		}
+
+		if r.URL.Query().Get("then") == "true" {
+			data, errs := group.Results()
+
+			return batchResults(results, data, errs), nil
+		}
+
+		resp := batchResponse{
+			ResultIDs: make([]string, len(results)),
+		}
+
+		for i, res := range results {
+			tenant.results.add(res, items[i].JobType, clientID(ctx), opts.ResultTTL)
+			resp.ResultIDs[i] = res.UUID()
+		}
+
+		return resp, nil
+	}
+}
+
+// parseBatch parses a JSON array of items, or newline-delimited JSON items (NDJSON)
+func parseBatch(body []byte) ([]batchItem, error) {
+	items := []batchItem{}
+
+	trimmed := bytes.TrimSpace(body)
+
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, errors.Wrap(err, "failed to Unmarshal")
+		}
+
+		return items, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(trimmed)+1)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		item := batchItem{}
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			return nil, errors.Wrapf(err, "failed to Unmarshal line %d", line)
+		}
+
+		items = append(items, item)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to Scan")
+	}
+
+	return items, nil
+}
+
+// bytes returns the item's data as job data. DataBase64 is used if present, JSON strings are used as-is,
+// and any other JSON value is used in its encoded form.
+func (b batchItem) bytes() []byte {
+	if b.DataBase64 != nil {
+		return b.DataBase64
+	}
+
+	if len(b.Data) == 0 || string(b.Data) == "null" {
+		return nil
+	}
+
+	str := ""
+	if err := json.Unmarshal(b.Data, &str); err == nil {
+		return []byte(str)
+	}
+
+	return b.Data
+}
+
+// batchResults collects the results of a batch's jobs, in order
+func batchResults(results []*rt.Result, data []interface{}, errs []error) []batchResult {
+	batch := make([]batchResult, len(results))
+
+	for i, res := range results {
+		batch[i] = batchResult{
+			ResultID: res.UUID(),
+			Status:   ResultStatusComplete,
+		}
+
+		if errs[i] != nil {
+			batch[i].Status = ResultStatusFailed
+			batch[i].Error = errs[i].Error()
+			continue
+		}
+
+		resultBytes, err := resultToBytes(data[i])
+		if err != nil {
+			batch[i].Status = ResultStatusFailed
+			batch[i].Error = err.Error()
+			continue
+		}
+
+		batch[i].Result = resultBytes
+	}
+
+	return batch
+}
@@ -0,0 +1,96 @@
+package rfaas
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	baseURL := startTestServer(t, nil)
+
+	status, body := doRequest(t, http.MethodPost, baseURL+"/batch", []byte(`[{"jobType":"echo","data":"one"},{"jobType":"echo","data":{"two":2}},{"jobType":"echo","dataBase64":"/wBmb3Vy"}]`))
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, string(body))
+	}
+
+	resp := batchResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.ResultIDs) != 3 {
+		t.Fatalf("expected 3 result IDs, got %d", len(resp.ResultIDs))
+	}
+
+	expected := []string{"one", `{"two":2}`, "\xff\x00four"}
+
+	for i, id := range resp.ResultIDs {
+		status, body := doRequest(t, http.MethodGet, baseURL+"/then/"+id, nil)
+		if status != http.StatusOK || string(body) != expected[i] {
+			t.Errorf("expected 200 %q, got %d %q", expected[i], status, string(body))
+		}
+	}
+}
+
+func TestBatchThen(t *testing.T) {
+	baseURL := startTestServer(t, nil)
+
+	ndjson := "{\"jobType\":\"echo\",\"data\":\"slow\"}\n{\"jobType\":\"echo\",\"data\":\"error\"}\n\n{\"jobType\":\"echo\",\"data\":\"three\"}\n"
+
+	status, body := doRequest(t, http.MethodPost, baseURL+"/batch?then=true", []byte(ndjson))
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, string(body))
+	}
+
+	results := []batchResult{}
+	if err := json.Unmarshal(body, &results); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	if results[0].Status != ResultStatusComplete || string(results[0].Result) != "slow" {
+		t.Errorf("unexpected first result %+v", results[0])
+	}
+
+	if results[1].Status != ResultStatusFailed || results[1].Error == "" {
+		t.Errorf("unexpected second result %+v", results[1])
+	}
+
+	if results[2].Status != ResultStatusComplete || string(results[2].Result) != "three" {
+		t.Errorf("unexpected third result %+v", results[2])
+	}
+}
+
+func TestBatchInvalid(t *testing.T) {
+	baseURL := startTestServer(t, func(s *Server) {
+		s.Configure(
+			UseMaxBatchSize(2),
+			UseAuthenticators(NewAPIKeyAuthenticator(map[string]string{"key": "a"})),
+			UseAuthorizer(Policy{"a": {"echo"}}),
+		)
+	})
+
+	cases := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"malformed", `[{"jobType":`, http.StatusBadRequest},
+		{"empty", `[]`, http.StatusBadRequest},
+		{"missing job type", `[{"data":"one"}]`, http.StatusBadRequest},
+		{"both data and dataBase64", `[{"jobType":"echo","data":"one","dataBase64":"b25l"}]`, http.StatusBadRequest},
+		{"too large", `[{"jobType":"echo"},{"jobType":"echo"},{"jobType":"echo"}]`, http.StatusRequestEntityTooLarge},
+		{"unauthorized job type", `[{"jobType":"echo"},{"jobType":"other"}]`, http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		status, body := doRequest(t, http.MethodPost, baseURL+"/batch", []byte(c.body), withHeader(HeaderAPIKey, "key"))
+		if status != c.expected {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.expected, status, string(body))
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StatusPending and others are the statuses of a job's result
+const (
+	StatusPending  = "pending"
+	StatusComplete = "complete"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+)
+
+// how long each request to /then waits for the result before it is made again
+const thenWait = time.Second * 30
+
+// Error is returned when the server responds with an error status
+type Error struct {
+	StatusCode int    `json:"status"`
+	Message    string `json:"message"`
+
+	// jobError is set if the server is reporting that a job failed
+	jobError *string
+}
+
+// errorResponse is the body of rfaas' error responses, which include a jobError when a job fails
+type errorResponse struct {
+	StatusCode int     `json:"status"`
+	Message    string  `json:"message"`
+	JobError   *string `json:"jobError"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// JobError is returned when a job fails, or its result is canceled
+type JobError struct {
+	ResultID string
+	Message  string
+}
+
+func (e *JobError) Error() string {
+	return fmt.Sprintf("job %s failed: %s", e.ResultID, e.Message)
+}
+
+// Status describes the state of a result
+type Status struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchJob is a job to be scheduled as part of a batch
+type BatchJob struct {
+	JobType string
+	Data    []byte
+}
+
+// batchItem sends the job's data base64 encoded, so that binary data isn't corrupted
+type batchItem struct {
+	JobType    string `json:"jobType"`
+	DataBase64 []byte `json:"dataBase64,omitempty"`
+}
+
+type batchResponse struct {
+	ResultIDs []string `json:"resultIds"`
+}
+
+type batchResult struct {
+	ResultID string `json:"resultId"`
+	Status   string `json:"status"`
+	Result   []byte `json:"result,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type doResponse struct {
+	ResultID string `json:"resultId"`
+}
+
+// Client makes requests to an rfaas server
+type Client struct {
+	baseURL string
+	options Options
+}
+
+// New creates a Client for the rfaas server at baseURL, for example http://localhost:8080
+func New(baseURL string, mods ...OptionsModifier) *Client {
+	options := defaultOptions()
+	for _, mod := range mods {
+		mod(&options)
+	}
+
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+
+	c := &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		options: options,
+	}
+
+	if options.Tenant != "" {
+		c.baseURL += "/t/" + url.PathEscape(options.Tenant)
+	}
+
+	return c
+}
+
+// Do schedules a job and returns a Result that can be used to wait for it
+func (c *Client) Do(ctx context.Context, jobType string, data []byte) (*Result, error) {
+	_, body, err := c.request(ctx, http.MethodPost, "/do/"+url.PathEscape(jobType), data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to request")
+	}
+
+	resp := doResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to Unmarshal response")
+	}
+
+	return c.Result(resp.ResultID), nil
+}
+
+// DoSync schedules a job and waits for it to complete, returning a completed Result. If the job fails, the Result's error is a *JobError.
+func (c *Client) DoSync(ctx context.Context, jobType string, data []byte) (*Result, error) {
+	_, body, err := c.request(ctx, http.MethodPost, "/do/"+url.PathEscape(jobType)+"?then=true", data)
+	if err != nil {
+		if jobErr := asJobError(err, ""); jobErr != nil {
+			return completedResult(c, "", nil, jobErr), nil
+		}
+
+		return nil, errors.Wrap(err, "failed to request")
+	}
+
+	return completedResult(c, "", body, nil), nil
+}
+
+// DoBatch schedules a batch of jobs, returning a Result for each of them in the same order
+func (c *Client) DoBatch(ctx context.Context, jobs []BatchJob) ([]*Result, error) {
+	body, err := batchBody(jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	_, respBody, err := c.request(ctx, http.MethodPost, "/batch", body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to request")
+	}
+
+	resp := batchResponse{}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to Unmarshal response")
+	}
+
+	results := make([]*Result, len(resp.ResultIDs))
+	for i, id := range resp.ResultIDs {
+		results[i] = c.Result(id)
+	}
+
+	return results, nil
+}
+
+// DoBatchSync schedules a batch of jobs and waits for all of them to complete, returning a completed Result for each of them in the same order
+func (c *Client) DoBatchSync(ctx context.Context, jobs []BatchJob) ([]*Result, error) {
+	body, err := batchBody(jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	_, respBody, err := c.request(ctx, http.MethodPost, "/batch?then=true", body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to request")
+	}
+
+	resp := []batchResult{}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to Unmarshal response")
+	}
+
+	results := make([]*Result, len(resp))
+	for i, res := range resp {
+		if res.Status != StatusComplete {
+			results[i] = completedResult(c, res.ResultID, nil, &JobError{ResultID: res.ResultID, Message: res.Error})
+		} else {
+			results[i] = completedResult(c, res.ResultID, res.Result, nil)
+		}
+	}
+
+	return results, nil
+}
+
+// Then waits for the result with the given ID and returns it. If the job failed, the error is a *JobError.
+// Results can only be fetched once if the server has no result TTL, so prefer Result.Then, which keeps the result.
+func (c *Client) Then(ctx context.Context, resultID string) ([]byte, error) {
+	path := fmt.Sprintf("/then/%s?wait=%s", url.PathEscape(resultID), thenWait)
+
+	for {
+		status, body, err := c.request(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			if jobErr := asJobError(err, resultID); jobErr != nil {
+				return nil, jobErr
+			}
+
+			return nil, errors.Wrap(err, "failed to request")
+		}
+
+		// the result is still pending after the wait, so try again
+		if status == http.StatusAccepted {
+			continue
+		}
+
+		return body, nil
+	}
+}
+
+// Status returns the status of the result with the given ID without waiting for it
+func (c *Client) Status(ctx context.Context, resultID string) (*Status, error) {
+	_, body, err := c.request(ctx, http.MethodGet, "/status/"+url.PathEscape(resultID), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to request")
+	}
+
+	status := &Status{}
+	if err := json.Unmarshal(body, status); err != nil {
+		return nil, errors.Wrap(err, "failed to Unmarshal status")
+	}
+
+	return status, nil
+}
+
+// Result returns a Result for a job that has already been scheduled
+func (c *Client) Result(resultID string) *Result {
+	r := &Result{
+		id:     resultID,
+		client: c,
+	}
+
+	return r
+}
+
+// request makes a request, retrying if it fails in a way that is safe to retry (see isRetryable).
+// Error responses are returned as *Error.
+func (c *Client) request(ctx context.Context, method, path string, body []byte) (int, []byte, error) {
+	attempts := c.options.RetryAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	backoff := c.options.RetryBackoff
+
+	var lastErr error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return 0, nil, ctx.Err()
+			}
+		}
+
+		status, respBody, err := c.attempt(ctx, method, path, body)
+		if err == nil {
+			return status, respBody, nil
+		}
+
+		// the context ending is final, and other errors either won't change on a retry or could cause a job to be scheduled twice
+		if ctx.Err() != nil {
+			return 0, nil, ctx.Err()
+		} else if !isRetryable(method, err) {
+			return 0, nil, err
+		}
+
+		lastErr = err
+	}
+
+	return 0, nil, errors.Wrapf(lastErr, "failed after %d attempts", attempts)
+}
+
+func (c *Client) attempt(ctx context.Context, method, path string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to NewRequest")
+	}
+
+	req = req.WithContext(ctx)
+
+	for key, vals := range c.options.Headers {
+		for _, val := range vals {
+			req.Header.Add(key, val)
+		}
+	}
+
+	if c.options.Authenticate != nil {
+		c.options.Authenticate(req, body)
+	}
+
+	resp, err := c.options.HTTPClient.Do(req)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to Do")
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to ReadAll")
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return 0, nil, errorFromResponse(resp.StatusCode, respBody)
+	}
+
+	return resp.StatusCode, respBody, nil
+}
+
+// errorFromResponse creates an *Error from an error response, which is JSON for errors that rfaas describes
+func errorFromResponse(status int, body []byte) *Error {
+	resp := errorResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Message == "" {
+		return &Error{StatusCode: status, Message: strings.TrimSpace(string(body))}
+	}
+
+	e := &Error{
+		StatusCode: status,
+		Message:    resp.Message,
+		jobError:   resp.JobError,
+	}
+
+	return e
+}
+
+// isRetryable returns true if retrying the request can't cause a job to be scheduled twice, and might succeed.
+// GET requests are retried after connection failures and responses saying the server is temporarily unavailable.
+// Other requests are only retried if they were never sent, or were rejected by the server's rate limit.
+func isRetryable(method string, err error) bool {
+	if e, isErr := errors.Cause(err).(*Error); isErr {
+		if method != http.MethodGet {
+			return e.StatusCode == http.StatusTooManyRequests
+		}
+
+		switch e.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+
+		return false
+	}
+
+	if method == http.MethodGet {
+		return true
+	}
+
+	// failing to connect means the request was never sent
+	opErr := &net.OpError{}
+	if errors.As(err, &opErr) {
+		return opErr.Op == "dial"
+	}
+
+	return false
+}
+
+// asJobError returns a *JobError if the error is rfaas reporting that a job failed, or nil otherwise
+func asJobError(err error, resultID string) *JobError {
+	e, isErr := errors.Cause(err).(*Error)
+	if !isErr || e.jobError == nil {
+		return nil
+	}
+
+	return &JobError{ResultID: resultID, Message: *e.jobError}
+}
+
+func batchBody(jobs []BatchJob) ([]byte, error) {
+	items := make([]batchItem, len(jobs))
+	for i, job := range jobs {
+		items[i] = batchItem{JobType: job.JobType, DataBase64: job.Data}
+	}
+
+	body, err := json.Marshal(items)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to Marshal batch")
+	}
+
+	return body, nil
+}
@@ -0,0 +1,339 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/reactr/rfaas"
+	"github.com/suborbital/reactr/rt"
+	"github.com/suborbital/vektor/vk"
+	"github.com/suborbital/vektor/vlog"
+)
+
+type echo struct{}
+
+func (e echo) Run(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
+	switch string(job.Bytes()) {
+	case "slow":
+		time.Sleep(time.Millisecond * 500)
+	case "error":
+		return nil, errors.New("bad")
+	}
+
+	return job.Bytes(), nil
+}
+
+func (e echo) OnChange(_ rt.ChangeEvent) error { return nil }
+
+// startTestServer starts an rfaas.Server on a free port and returns its base URL
+func startTestServer(t *testing.T, setup func(*rfaas.Server)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	server := rfaas.New(vk.UseInsecureHTTP(port), vk.UseLogger(vlog.Default(vlog.Level(vlog.LogLevelError))))
+	server.Handle("echo", echo{})
+
+	if setup != nil {
+		setup(server)
+	}
+
+	go server.Start()
+
+	// wait for the server to start listening
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port)); err == nil {
+			conn.Close()
+			return fmt.Sprintf("http://127.0.0.1:%d", port)
+		}
+
+		time.Sleep(time.Millisecond * 20)
+	}
+
+	t.Fatal("server did not start")
+
+	return ""
+}
+
+func TestDo(t *testing.T) {
+	// with no TTL, the server only returns a result once, so Result must keep it
+	c := New(startTestServer(t, func(s *rfaas.Server) {
+		s.Configure(rfaas.UseResultTTL(0))
+	}))
+
+	ctx := context.Background()
+
+	res, err := c.Do(ctx, "echo", []byte(`{"hello":"world"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.ID() == "" {
+		t.Error("expected result ID")
+	}
+
+	out := map[string]string{}
+	if err := res.ThenJSON(ctx, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out["hello"] != "world" {
+		t.Errorf("unexpected result %+v", out)
+	}
+
+	if data, err := res.Then(ctx); err != nil || string(data) != `{"hello":"world"}` {
+		t.Errorf("expected kept result, got %q %v", string(data), err)
+	}
+
+	res, err = c.Do(ctx, "echo", []byte("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = res.Then(ctx)
+
+	jobErr, isJobErr := err.(*JobError)
+	if !isJobErr || jobErr.Message != "bad" || jobErr.ResultID != res.ID() {
+		t.Errorf("expected JobError, got %v", err)
+	}
+
+	// jobs are scheduled even if their job type isn't handled, and fail instead
+	res, err = c.Do(ctx, "nope", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := res.Then(ctx); err == nil {
+		t.Error("expected error for unhandled job type")
+	}
+}
+
+func TestDoSync(t *testing.T) {
+	c := New(startTestServer(t, nil))
+	ctx := context.Background()
+
+	res, err := c.DoSync(ctx, "echo", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := res.Then(ctx); err != nil || string(data) != "hello" {
+		t.Errorf("unexpected result %q %v", string(data), err)
+	}
+
+	res, err = c.DoSync(ctx, "echo", []byte("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := res.Then(ctx); err == nil {
+		t.Error("expected job error")
+	}
+
+	if status, err := res.Status(ctx); err != nil || status.Status != StatusFailed {
+		t.Errorf("expected failed status, got %+v %v", status, err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	c := New(startTestServer(t, nil))
+	ctx := context.Background()
+
+	res, err := c.Do(ctx, "echo", []byte("slow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status, err := c.Status(ctx, res.ID()); err != nil || status.Status != StatusPending {
+		t.Errorf("expected pending status, got %+v %v", status, err)
+	}
+
+	if _, err := res.Then(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, err := res.Status(ctx); err != nil || status.Status != StatusComplete {
+		t.Errorf("expected complete status, got %+v %v", status, err)
+	}
+
+	_, err = c.Status(ctx, "00000000-0000-0000-0000-000000000000")
+	if e, isErr := errors.Cause(err).(*Error); !isErr || e.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 Error, got %v", err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	c := New(startTestServer(t, nil))
+	ctx := context.Background()
+
+	jobs := []BatchJob{{JobType: "echo", Data: []byte("one")}, {JobType: "echo", Data: []byte("error")}}
+
+	results, err := c.DoBatch(ctx, jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	syncResults, err := c.DoBatchSync(ctx, jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, batch := range [][]*Result{results, syncResults} {
+		if len(batch) != 2 {
+			t.Fatalf("expected 2 results, got %d", len(batch))
+		}
+
+		if data, err := batch[0].Then(ctx); err != nil || string(data) != "one" {
+			t.Errorf("unexpected result %q %v", string(data), err)
+		}
+
+		if _, err := batch[1].Then(ctx); err == nil {
+			t.Error("expected job error")
+		}
+	}
+
+	// binary data must arrive unchanged
+	binary := []byte{0xff, 0xfe, 0x00, 'h', 'i', 0x80}
+
+	binaryResults, err := c.DoBatchSync(ctx, []BatchJob{{JobType: "echo", Data: binary}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := binaryResults[0].Then(ctx); err != nil || !bytes.Equal(data, binary) {
+		t.Errorf("unexpected binary result %v %v", data, err)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	baseURL := startTestServer(t, func(s *rfaas.Server) {
+		s.Configure(rfaas.UseAuthenticators(
+			rfaas.NewAPIKeyAuthenticator(map[string]string{"key": "a"}),
+			rfaas.NewHMACAuthenticator(map[string][]byte{"b": []byte("secret")}),
+		))
+	})
+
+	ctx := context.Background()
+
+	for _, mod := range []OptionsModifier{UseAPIKey("key"), UseHMAC("b", []byte("secret"))} {
+		res, err := New(baseURL, mod).DoSync(ctx, "echo", []byte("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if data, err := res.Then(ctx); err != nil || string(data) != "hello" {
+			t.Errorf("unexpected result %q %v", string(data), err)
+		}
+	}
+
+	_, err := New(baseURL, UseAPIKey("wrong")).DoSync(ctx, "echo", []byte("hello"))
+	if e, isErr := errors.Cause(err).(*Error); !isErr || e.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 Error, got %v", err)
+	}
+}
+
+func TestTenant(t *testing.T) {
+	baseURL := startTestServer(t, func(s *rfaas.Server) {
+		s.Configure(rfaas.UseAuthenticators(rfaas.NewAPIKeyAuthenticator(map[string]string{"key": "a"})))
+
+		tenant, err := s.AddTenant("alpha", rfaas.UseTenantClients("a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tenant.Handle("tenantecho", echo{})
+	})
+
+	ctx := context.Background()
+
+	res, err := New(baseURL, UseAPIKey("key"), UseTenant("alpha")).Do(ctx, "tenantecho", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := res.Then(ctx); err != nil || string(data) != "hello" {
+		t.Errorf("unexpected result %q %v", string(data), err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	count := int32(0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte(`{"id":"abc","status":"pending"}`))
+	}))
+
+	defer server.Close()
+
+	ctx := context.Background()
+
+	if status, err := New(server.URL, UseRetry(3, time.Millisecond)).Status(ctx, "abc"); err != nil || status.ID != "abc" {
+		t.Errorf("expected status after retries, got %+v %v", status, err)
+	}
+
+	atomic.StoreInt32(&count, 0)
+
+	if _, err := New(server.URL, UseRetry(2, time.Millisecond)).Status(ctx, "abc"); err == nil {
+		t.Error("expected error after running out of attempts")
+	}
+
+	// the server may have scheduled the job before responding, so scheduling isn't retried
+	atomic.StoreInt32(&count, 0)
+
+	if _, err := New(server.URL, UseRetry(3, time.Millisecond)).Do(ctx, "echo", nil); err == nil || atomic.LoadInt32(&count) != 1 {
+		t.Errorf("expected one attempt to schedule, got %d %v", atomic.LoadInt32(&count), err)
+	}
+
+	// unless it was never sent
+	if _, err := New("http://127.0.0.1:1", UseRetry(2, time.Millisecond)).Do(ctx, "echo", nil); err == nil || !strings.Contains(err.Error(), "failed after 2 attempts") {
+		t.Errorf("expected retried connection failure, got %v", err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	c := New(startTestServer(t, nil))
+
+	res, err := c.Do(context.Background(), "echo", []byte("slow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	// Status can be used while Then is waiting
+	go res.Then(ctx)
+
+	time.Sleep(time.Millisecond * 10)
+
+	if status, err := res.Status(context.Background()); err != nil || status.Status != StatusPending {
+		t.Errorf("expected pending status, got %+v %v", status, err)
+	}
+
+	if _, err := res.Then(ctx); err == nil {
+		t.Fatal("expected context error")
+	}
+
+	// the result wasn't kept, so it can be waited for again
+	if data, err := res.Then(context.Background()); err != nil || string(data) != "slow" {
+		t.Errorf("unexpected result %q %v", string(data), err)
+	}
+}
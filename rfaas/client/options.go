@@ -0,0 +1,130 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryAttempts = 3
+	defaultRetryBackoff  = time.Millisecond * 250
+)
+
+// these must match the headers used by rfaas
+const (
+	headerAPIKey     = "X-Reactr-API-Key"
+	headerClientID   = "X-Reactr-Client"
+	headerTimestamp  = "X-Reactr-Timestamp"
+	headerSignature  = "X-Reactr-Signature"
+	headerMetaPrefix = "X-Reactr-Meta-"
+)
+
+// Options are the options for a Client
+type Options struct {
+	// HTTPClient is used to make requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// RetryAttempts is the number of times a request is attempted before giving up, with RetryBackoff as the
+	// delay before the first retry, doubling for each one after that. GET requests are retried if they fail
+	// to connect, or receive a 429, 502, 503, or 504 response. Requests that schedule jobs are only retried
+	// if they fail to connect or receive a 429 response, so that a job is never scheduled twice.
+	RetryAttempts int
+	RetryBackoff  time.Duration
+
+	// Tenant, if set, is the name of the rfaas.Tenant that requests are made to
+	Tenant string
+
+	// Headers are added to every request, for example to add job metadata
+	Headers http.Header
+
+	// Authenticate, if set, is called with each request and its body to add credentials
+	Authenticate func(r *http.Request, body []byte)
+}
+
+// OptionsModifier modifies a Client's Options
+type OptionsModifier func(*Options)
+
+func defaultOptions() Options {
+	o := Options{
+		HTTPClient:    http.DefaultClient,
+		RetryAttempts: defaultRetryAttempts,
+		RetryBackoff:  defaultRetryBackoff,
+		Headers:       http.Header{},
+	}
+
+	return o
+}
+
+// UseHTTPClient sets the http.Client used to make requests
+func UseHTTPClient(client *http.Client) OptionsModifier {
+	return func(opts *Options) {
+		opts.HTTPClient = client
+	}
+}
+
+// UseRetry sets how many times requests are attempted, and the delay before the first retry
+func UseRetry(attempts int, backoff time.Duration) OptionsModifier {
+	return func(opts *Options) {
+		opts.RetryAttempts = attempts
+		opts.RetryBackoff = backoff
+	}
+}
+
+// UseTenant makes requests to one of the server's Tenants rather than the server itself
+func UseTenant(name string) OptionsModifier {
+	return func(opts *Options) {
+		opts.Tenant = name
+	}
+}
+
+// UseMeta adds job metadata to every job scheduled by the Client
+func UseMeta(key, val string) OptionsModifier {
+	return func(opts *Options) {
+		opts.Headers.Set(headerMetaPrefix+key, val)
+	}
+}
+
+// UseAPIKey authenticates requests with an API key (see rfaas.NewAPIKeyAuthenticator)
+func UseAPIKey(key string) OptionsModifier {
+	return func(opts *Options) {
+		opts.Authenticate = func(r *http.Request, _ []byte) {
+			r.Header.Set(headerAPIKey, key)
+		}
+	}
+}
+
+// UseBearerToken authenticates requests with a bearer token, such as a JWT (see rfaas.NewJWTAuthenticator)
+func UseBearerToken(token string) OptionsModifier {
+	return func(opts *Options) {
+		opts.Authenticate = func(r *http.Request, _ []byte) {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// UseHMAC authenticates requests by signing them with the client's secret (see rfaas.NewHMACAuthenticator)
+func UseHMAC(clientID string, secret []byte) OptionsModifier {
+	return func(opts *Options) {
+		opts.Authenticate = func(r *http.Request, body []byte) {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+			r.Header.Set(headerClientID, clientID)
+			r.Header.Set(headerTimestamp, timestamp)
+			r.Header.Set(headerSignature, requestSignature(secret, timestamp, r.Method, r.URL.RequestURI(), body))
+		}
+	}
+}
+
+// requestSignature is the hex-encoded HMAC-SHA256 of the timestamp, method, URI, and body, separated by newlines, as in rfaas.SignRequest
+func requestSignature(secret []byte, timestamp, method, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(fmt.Sprintf("%s\n%s\n%s\n", timestamp, method, uri)))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
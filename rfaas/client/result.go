@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Result is the result of a job scheduled using a Client. Unlike rt.Result, Then can be called any number of
+// times, since the result is kept once it has been fetched.
+type Result struct {
+	id     string
+	client *Client
+	data   []byte
+	err    error
+	done   bool
+
+	// fetching is closed once a call to Then that is waiting for the result returns
+	fetching chan struct{}
+	lock     sync.Mutex
+}
+
+func completedResult(client *Client, id string, data []byte, err error) *Result {
+	r := &Result{
+		id:     id,
+		client: client,
+		data:   data,
+		err:    err,
+		done:   true,
+	}
+
+	return r
+}
+
+// ID returns the result's ID, which is empty for results of DoSync
+func (r *Result) ID() string {
+	return r.id
+}
+
+// Then waits for the result and returns it. If the job failed, the error is a *JobError.
+func (r *Result) Then(ctx context.Context) ([]byte, error) {
+	for {
+		r.lock.Lock()
+
+		if r.done {
+			data, err := r.data, r.err
+			r.lock.Unlock()
+
+			return data, err
+		}
+
+		// results may only be fetched once, so wait for any other call that is already fetching it
+		if fetching := r.fetching; fetching != nil {
+			r.lock.Unlock()
+
+			select {
+			case <-fetching:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		fetching := make(chan struct{})
+		r.fetching = fetching
+		r.lock.Unlock()
+
+		data, err := r.client.Then(ctx, r.id)
+
+		r.lock.Lock()
+
+		// keep the result unless the request itself failed, in which case it can be tried again
+		if _, isJobErr := err.(*JobError); err == nil || isJobErr {
+			r.data, r.err, r.done = data, err, true
+		}
+
+		r.fetching = nil
+		close(fetching)
+		r.lock.Unlock()
+
+		return data, err
+	}
+}
+
+// ThenJSON waits for the result and unmarshals it into out
+func (r *Result) ThenJSON(ctx context.Context, out interface{}) error {
+	data, err := r.Then(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return errors.Wrap(err, "failed to Unmarshal result")
+	}
+
+	return nil
+}
+
+// Status returns the result's status without waiting for it
+func (r *Result) Status(ctx context.Context) (*Status, error) {
+	r.lock.Lock()
+	done, err := r.done, r.err
+	r.lock.Unlock()
+
+	if done && r.id == "" {
+		status := &Status{Status: StatusComplete}
+		if err != nil {
+			status.Status = StatusFailed
+			status.Error = err.Error()
+		}
+
+		return status, nil
+	}
+
+	return r.client.Status(ctx, r.id)
+}
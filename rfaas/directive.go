@@ -0,0 +1,220 @@
+package rfaas
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/suborbital/reactr/bundle"
+	"github.com/suborbital/reactr/directive"
+	"github.com/suborbital/reactr/request"
+	"github.com/suborbital/reactr/rt"
+	"github.com/suborbital/vektor/vk"
+)
+
+// HandleBundleAtPath loads a .wasm.zip bundle and serves it (see HandleBundle)
+func (s *Server) HandleBundleAtPath(path string) error {
+	b, err := bundle.Read(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to Read bundle")
+	}
+
+	return s.HandleBundle(b)
+}
+
+// HandleBundle handles each of the bundle's Runnables and mounts its Directive's handlers as HTTP routes.
+// It must be called before the Server is started.
+func (s *Server) HandleBundle(b *bundle.Bundle) error {
+	if _, err := s.handleBundleRunnables(s.root, b, nil); err != nil {
+		return err
+	}
+
+	return s.MountDirective(b.Directive)
+}
+
+// MountDirective mounts each of the Directive's request handlers as an HTTP route that runs the handler's steps.
+// The Runnables it uses must already be handled using their FQFNs. It must be called before the Server is started.
+func (s *Server) MountDirective(d *directive.Directive) error {
+	if err := d.Validate(); err != nil {
+		return errors.Wrap(err, "failed to Validate directive")
+	}
+
+	routes := vk.Group("").Before(s.limitMiddleware(), s.authMiddleware(), s.rootMiddleware())
+
+	for _, h := range d.Handlers {
+		if h.Input.Type != directive.InputTypeRequest {
+			continue
+		}
+
+		routes.Handle(strings.ToUpper(h.Input.Method), h.Input.Resource, s.directiveHandler(d, h))
+	}
+
+	s.AddGroup(routes)
+
+	return nil
+}
+
+// directiveHandler runs the handler's steps and responds with its response state key
+func (s *Server) directiveHandler(d *directive.Directive, h directive.Handler) vk.HandlerFunc {
+	// if no response key is set, the last step must be a single fn (ensured by Validate), and its result is the response
+	responseKey := h.Response
+	if responseKey == "" {
+		responseKey = stateKey(h.Steps[len(h.Steps)-1].CallableFn)
+	}
+
+	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
+		req, err := request.FromVKRequest(r, ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// Runnables need an ID to recognize the job as a request
+		if req.ID == "" {
+			req.ID = uuid.New().String()
+		}
+
+		if err := s.authorizeSteps(ctx, d, h.Steps); err != nil {
+			return nil, err
+		}
+
+		if err := s.runSteps(d, h.Steps, req); err != nil {
+			ctx.Log.Error(errors.Wrapf(err, "failed to run handler for %s %s", h.Input.Method, h.Input.Resource))
+			return nil, vk.E(http.StatusInternalServerError, errors.Wrap(err, "handler resulted in error").Error())
+		}
+
+		return req.State[responseKey], nil
+	}
+}
+
+// authorizeSteps ensures the client is allowed to run every fn in the steps, using their FQFNs as job types,
+// so that a handler is never partially run
+func (s *Server) authorizeSteps(ctx *vk.Ctx, d *directive.Directive, steps []directive.Executable) error {
+	for _, step := range steps {
+		fns := step.Group
+		if step.IsFn() {
+			fns = []directive.CallableFn{step.CallableFn}
+		}
+
+		for _, fn := range fns {
+			fqfn, err := d.FQFN(fn.Fn)
+			if err != nil {
+				return errors.Wrapf(err, "failed to FQFN %s", fn.Fn)
+			}
+
+			if err := s.authorize(ctx, fqfn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// runSteps runs each step in order, adding the result of each fn to the request's state.
+// The fns in a group step are run concurrently.
+func (s *Server) runSteps(d *directive.Directive, steps []directive.Executable, req *request.CoordinatedRequest) error {
+	for _, step := range steps {
+		fns := step.Group
+		if step.IsFn() {
+			fns = []directive.CallableFn{step.CallableFn}
+		}
+
+		results := make([]*rt.Result, 0, len(fns))
+
+		// if the step fails, the results that haven't been read are discarded
+		discard := func(from int) {
+			for _, res := range results[from:] {
+				res.Discard()
+			}
+		}
+
+		for i := range fns {
+			job, err := jobForFn(d, fns[i], req)
+			if err != nil {
+				discard(0)
+				return errors.Wrapf(err, "failed to create job for fn %s", fns[i].Fn)
+			}
+
+			results = append(results, s.Do(job))
+		}
+
+		for i, res := range results {
+			output, err := res.Then()
+			if err != nil {
+				discard(i + 1)
+				return errors.Wrapf(err, "fn %s failed", fns[i].Fn)
+			}
+
+			outputBytes, err := resultToBytes(output)
+			if err != nil {
+				discard(i + 1)
+				return errors.Wrapf(err, "failed to get result of fn %s", fns[i].Fn)
+			}
+
+			req.State[stateKey(fns[i])] = outputBytes
+		}
+	}
+
+	return nil
+}
+
+// jobForFn creates a job for the fn, containing the request along with the state the fn asks for using `with`
+func jobForFn(d *directive.Directive, fn directive.CallableFn, req *request.CoordinatedRequest) (rt.Job, error) {
+	fqfn, err := d.FQFN(fn.Fn)
+	if err != nil {
+		return rt.Job{}, errors.Wrap(err, "failed to FQFN")
+	}
+
+	desired, err := fn.ParseWith()
+	if err != nil {
+		return rt.Job{}, errors.Wrap(err, "failed to ParseWith")
+	}
+
+	fnReq := *req
+
+	// without `with`, the fn gets the handler's full state
+	if len(desired) > 0 {
+		fnReq.State = map[string][]byte{}
+
+		for _, alias := range desired {
+			fnReq.State[alias.Alias] = req.State[alias.Key]
+		}
+	}
+
+	reqJSON, err := fnReq.ToJSON()
+	if err != nil {
+		return rt.Job{}, errors.Wrap(err, "failed to ToJSON")
+	}
+
+	return rt.NewJob(fqfn, reqJSON), nil
+}
+
+// stateKey is the key the fn's result is stored under in the handler's state
+func stateKey(fn directive.CallableFn) string {
+	if fn.As != "" {
+		return fn.As
+	}
+
+	return fn.Fn
+}
+
+// resultToBytes returns byte and string results as-is, and attempts to JSON marshal anything else
+func resultToBytes(result interface{}) ([]byte, error) {
+	switch r := result.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return r, nil
+	case string:
+		return []byte(r), nil
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to Marshal result")
+	}
+
+	return resultJSON, nil
+}
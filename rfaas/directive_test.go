@@ -0,0 +1,181 @@
+package rfaas
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/reactr/directive"
+	"github.com/suborbital/reactr/request"
+	"github.com/suborbital/reactr/rt"
+	"github.com/suborbital/vektor/vk"
+)
+
+// requestFn is a Go Runnable that receives a request, standing in for a Wasm Runnable from a bundle
+type requestFn func(req *request.CoordinatedRequest) (interface{}, error)
+
+func (r requestFn) Run(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
+	req, err := request.FromJSON(job.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return r(req)
+}
+
+func (r requestFn) OnChange(_ rt.ChangeEvent) error { return nil }
+
+func testDirective() *directive.Directive {
+	return &directive.Directive{
+		Identifier:  "com.suborbital.test",
+		AppVersion:  "v0.1.0",
+		AtmoVersion: "v0.0.6",
+		Runnables: []directive.Runnable{
+			{Name: "greet", Namespace: "default"},
+			{Name: "shout", Namespace: "default"},
+			{Name: "count", Namespace: "default"},
+			{Name: "fail", Namespace: "default"},
+		},
+		Handlers: []directive.Handler{
+			{
+				Input: directive.Input{Type: directive.InputTypeRequest, Method: "POST", Resource: "/hello/:name"},
+				Steps: []directive.Executable{
+					{CallableFn: directive.CallableFn{Fn: "greet"}},
+					{Group: []directive.CallableFn{
+						{Fn: "shout", With: []string{"msg: greet"}},
+						{Fn: "count", As: "length"},
+					}},
+				},
+				Response: "shout",
+			},
+			{
+				Input: directive.Input{Type: directive.InputTypeRequest, Method: "GET", Resource: "/length/:name"},
+				Steps: []directive.Executable{
+					{CallableFn: directive.CallableFn{Fn: "greet"}},
+					{CallableFn: directive.CallableFn{Fn: "count", As: "length"}},
+				},
+			},
+			{
+				Input: directive.Input{Type: directive.InputTypeRequest, Method: "GET", Resource: "/fail"},
+				Steps: []directive.Executable{
+					{CallableFn: directive.CallableFn{Fn: "fail"}},
+				},
+			},
+		},
+	}
+}
+
+func TestMountDirective(t *testing.T) {
+	d := testDirective()
+
+	fns := map[string]requestFn{
+		"greet": func(req *request.CoordinatedRequest) (interface{}, error) {
+			return fmt.Sprintf("%s %s", string(req.Body), req.Params["name"]), nil
+		},
+		"shout": func(req *request.CoordinatedRequest) (interface{}, error) {
+			// with `with`, only the aliased state is available
+			if _, exists := req.State["greet"]; exists {
+				return nil, errors.New("unexpected state")
+			}
+
+			return strings.ToUpper(string(req.State["msg"])), nil
+		},
+		"count": func(req *request.CoordinatedRequest) (interface{}, error) {
+			return len(req.State["greet"]), nil
+		},
+		"fail": func(req *request.CoordinatedRequest) (interface{}, error) {
+			return nil, errors.New("failed")
+		},
+	}
+
+	baseURL := startTestServer(t, func(s *Server) {
+		for name, fn := range fns {
+			fqfn, err := d.FQFN(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s.Handle(fqfn, fn)
+		}
+
+		if err := s.MountDirective(d); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	status, body := doRequest(t, http.MethodPost, baseURL+"/hello/reactr", []byte("hello"))
+	if status != http.StatusOK || string(body) != "HELLO REACTR" {
+		t.Errorf("expected 200 'HELLO REACTR', got %d %q", status, string(body))
+	}
+
+	// without a response key, the last fn's result is the response
+	status, body = doRequest(t, http.MethodGet, baseURL+"/length/reactr", nil)
+	if status != http.StatusOK || string(body) != "7" {
+		t.Errorf("expected 200 '7', got %d %q", status, string(body))
+	}
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/fail", nil); status != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", status)
+	}
+}
+
+func TestMountDirectiveAuthorize(t *testing.T) {
+	d := testDirective()
+
+	ran := int32(0)
+
+	greetFQFN, _ := d.FQFN("greet")
+	countFQFN, _ := d.FQFN("count")
+
+	baseURL := startTestServer(t, func(s *Server) {
+		for _, name := range []string{"greet", "shout", "count"} {
+			fqfn, _ := d.FQFN(name)
+
+			s.Handle(fqfn, requestFn(func(req *request.CoordinatedRequest) (interface{}, error) {
+				atomic.AddInt32(&ran, 1)
+				return "ok", nil
+			}))
+		}
+
+		s.Configure(
+			UseAuthenticators(NewAPIKeyAuthenticator(map[string]string{"key-a": "a"})),
+			UseAuthorizer(Policy{"a": {greetFQFN, countFQFN}}),
+		)
+
+		if err := s.MountDirective(d); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	a := withHeader(HeaderAPIKey, "key-a")
+
+	if status, body := doRequest(t, http.MethodGet, baseURL+"/length/reactr", nil, a); status != http.StatusOK {
+		t.Errorf("expected 200, got %d %q", status, string(body))
+	}
+
+	// the client isn't allowed to run shout, so none of the handler's fns are run
+	atomic.StoreInt32(&ran, 0)
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/hello/reactr", []byte("hello"), a); status != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", status)
+	}
+
+	if count := atomic.LoadInt32(&ran); count != 0 {
+		t.Errorf("expected no fns to run, got %d", count)
+	}
+}
+
+func TestMountDirectiveInvalid(t *testing.T) {
+	d := testDirective()
+	d.Handlers[0].Steps[0].Fn = "missing"
+
+	// the server is never started, so the port doesn't matter
+	server := New(vk.UseInsecureHTTP(8080))
+
+	if err := server.MountDirective(d); err == nil {
+		t.Error("expected error for invalid directive")
+	}
+}
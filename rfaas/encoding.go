@@ -0,0 +1,172 @@
+package rfaas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/vektor/vk"
+)
+
+const (
+	contentTypeJSON        = "application/json"
+	contentTypeOctetStream = "application/octet-stream"
+	contentTypeTextPlain   = "text/plain; charset=utf-8"
+)
+
+// metaContentType is the job metadata key containing the Content-Type of the request that scheduled it
+const metaContentType = "content-type"
+
+// errBodyTooLarge is returned when reading more than the maximum body size from a request
+var errBodyTooLarge = errors.New("request body too large")
+
+// limitMiddleware limits the size of request bodies to the Server's MaxBodySize
+func (s *Server) limitMiddleware() vk.Middleware {
+	return func(r *http.Request, ctx *vk.Ctx) error {
+		max := s.getOptions().MaxBodySize
+		if max <= 0 {
+			return nil
+		}
+
+		if r.ContentLength > max {
+			return vk.E(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is larger than %d bytes", max))
+		}
+
+		// the Content-Length can be missing (or wrong), so limit what can actually be read as well
+		r.Body = &limitedBody{body: r.Body, remaining: max}
+
+		return nil
+	}
+}
+
+// limitedBody is a request body that returns errBodyTooLarge once more than its limit has been read
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, errBodyTooLarge
+	}
+
+	// read one byte more than remains to find out if the body is too large
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.body.Read(p)
+	if int64(n) <= l.remaining {
+		l.remaining -= int64(n)
+		return n, err
+	}
+
+	n = int(l.remaining)
+	l.remaining = 0
+	l.exceeded = true
+
+	return n, errBodyTooLarge
+}
+
+func (l *limitedBody) Close() error {
+	return l.body.Close()
+}
+
+// readBody reads and closes the request body, returning a vk error if it fails
+func readBody(r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err == errBodyTooLarge {
+		return nil, vk.E(http.StatusRequestEntityTooLarge, "request body is too large")
+	} else if err != nil {
+		return nil, vk.E(http.StatusInternalServerError, "failed to read request body")
+	}
+
+	return body, nil
+}
+
+// resultResponse chooses how to represent a job's result based on the request's Accept header. Byte results are
+// returned raw with their detected content type (or as application/octet-stream), strings as text, and anything
+// else as JSON. If the client doesn't accept any of those, the response has status 406 Not Acceptable.
+func resultResponse(r *http.Request, ctx *vk.Ctx, result interface{}) (interface{}, error) {
+	accept := r.Header.Get("Accept")
+
+	var body []byte
+	var offered []string
+
+	switch res := result.(type) {
+	case []byte:
+		body = res
+		offered = []string{detectContentType(res), contentTypeOctetStream}
+	case string:
+		body = []byte(res)
+		offered = []string{contentTypeTextPlain, contentTypeOctetStream}
+	default:
+		if !accepts(accept, contentTypeJSON) {
+			return nil, vk.E(http.StatusNotAcceptable, "result can only be represented as application/json")
+		}
+
+		// vk JSON-encodes the result and sets the content type
+		return result, nil
+	}
+
+	for _, contentType := range offered {
+		if accepts(accept, contentType) {
+			ctx.RespHeaders.Set("Content-Type", contentType)
+			return body, nil
+		}
+	}
+
+	return nil, vk.E(http.StatusNotAcceptable, fmt.Sprintf("result can only be represented as %s", strings.Join(offered, " or ")))
+}
+
+// detectContentType detects the content type of a byte result, recognizing JSON in addition to http.DetectContentType's types
+func detectContentType(data []byte) string {
+	if len(data) > 0 && json.Valid(data) {
+		return contentTypeJSON
+	}
+
+	return http.DetectContentType(data)
+}
+
+// accepts returns true if the Accept header allows the content type. An empty header accepts anything.
+func accepts(accept, contentType string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		acceptType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		// a quality of 0 means "not acceptable"
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			continue
+		}
+
+		if acceptType == "*/*" || acceptType == mediaType {
+			return true
+		}
+
+		if strings.HasSuffix(acceptType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(acceptType, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
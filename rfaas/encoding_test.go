@@ -0,0 +1,93 @@
+package rfaas
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/suborbital/reactr/rt"
+)
+
+type structResult struct{}
+
+func (s structResult) Run(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
+	return map[string]string{"hello": string(job.Bytes())}, nil
+}
+
+func (s structResult) OnChange(_ rt.ChangeEvent) error { return nil }
+
+func TestMaxBodySize(t *testing.T) {
+	baseURL := startTestServer(t, func(s *Server) {
+		s.Configure(UseMaxBodySize(8))
+	})
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/do/echo?then=true", []byte("12345678")); status != http.StatusOK {
+		t.Errorf("expected 200 at the limit, got %d", status)
+	}
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/do/echo?then=true", []byte("123456789")); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 with Content-Length over the limit, got %d", status)
+	}
+
+	// without a Content-Length, the limit is enforced while reading
+	chunked := func(r *http.Request) {
+		r.ContentLength = -1
+		r.Body = ioutil.NopCloser(bytes.NewReader([]byte("123456789")))
+	}
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/do/echo?then=true", nil, chunked); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for chunked body over the limit, got %d", status)
+	}
+}
+
+func TestResultEncoding(t *testing.T) {
+	baseURL := startTestServer(t, func(s *Server) {
+		s.Handle("struct", structResult{})
+	})
+
+	cases := []struct {
+		name        string
+		jobType     string
+		body        string
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"text", "echo", "hello", "", http.StatusOK, "text/plain; charset=utf-8"},
+		{"json bytes", "echo", `{"hello":"world"}`, "", http.StatusOK, "application/json"},
+		{"binary", "echo", "\x89PNG\r\n\x1a\n", "", http.StatusOK, "image/png"},
+		{"accept octet-stream", "echo", "hello", "application/octet-stream", http.StatusOK, "application/octet-stream"},
+		{"accept wildcard subtype", "echo", "hello", "text/*", http.StatusOK, "text/plain; charset=utf-8"},
+		{"not acceptable bytes", "echo", "hello", "application/json", http.StatusNotAcceptable, ""},
+		{"struct", "struct", "world", "application/json, text/plain;q=0.5", http.StatusOK, "application/json"},
+		{"not acceptable struct", "struct", "world", "text/plain", http.StatusNotAcceptable, ""},
+		{"q=0", "echo", "hello", "text/plain;q=0, application/octet-stream", http.StatusOK, "application/octet-stream"},
+	}
+
+	for _, c := range cases {
+		status, headers, body := doRequestWithHeaders(t, http.MethodPost, baseURL+"/do/"+c.jobType+"?then=true", []byte(c.body), withHeader("Accept", c.accept))
+		if status != c.status {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.status, status, string(body))
+			continue
+		}
+
+		if c.contentType != "" && headers.Get("Content-Type") != c.contentType {
+			t.Errorf("%s: expected content type %s, got %s", c.name, c.contentType, headers.Get("Content-Type"))
+		}
+
+		if status == http.StatusOK && c.jobType == "echo" && string(body) != c.body {
+			t.Errorf("%s: expected raw body %q, got %q", c.name, c.body, string(body))
+		}
+	}
+}
+
+func TestContentTypeMeta(t *testing.T) {
+	baseURL := startTestServer(t, nil)
+
+	_, headers, _ := doRequestWithHeaders(t, http.MethodPost, baseURL+"/do/echo?then=true", []byte("{}"), withHeader("Content-Type", "application/json"))
+
+	if meta := headers.Get(headerMetaPrefix + "Content-Type"); meta != "application/json" {
+		t.Errorf("expected content-type metadata, got %q", meta)
+	}
+}
@@ -0,0 +1,369 @@
+package rfaas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/suborbital/vektor/vk"
+)
+
+// how often a comment is sent on idle SSE streams to keep them open
+const eventKeepAliveInterval = time.Second * 15
+
+// resultEvent is sent to subscribers when a result completes
+type resultEvent struct {
+	ID      string `json:"id"`
+	JobType string `json:"jobType"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Result  []byte `json:"result,omitempty"`
+}
+
+// subscriber receives events for a set of result IDs and job types, for results belonging to its client
+type subscriber struct {
+	clientID string
+	ids      map[string]bool
+	jobTypes map[string]bool
+	events   chan resultEvent
+	done     chan struct{}
+	lock     sync.Mutex
+}
+
+func newSubscriber(clientID string) *subscriber {
+	s := &subscriber{
+		clientID: clientID,
+		ids:      map[string]bool{},
+		jobTypes: map[string]bool{},
+		events:   make(chan resultEvent, 64),
+		done:     make(chan struct{}),
+		lock:     sync.Mutex{},
+	}
+
+	return s
+}
+
+// take returns true if the subscriber wants the record's event. A subscription to a result's ID
+// is removed once its event is taken, so that each result is only delivered once.
+func (s *subscriber) take(record *resultRecord) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if record.clientID != s.clientID {
+		return false
+	}
+
+	if s.ids[record.id] {
+		delete(s.ids, record.id)
+		return true
+	}
+
+	return s.jobTypes[record.jobType]
+}
+
+// waiting returns true if the subscriber is waiting for anything
+func (s *subscriber) waiting() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.ids) > 0 || len(s.jobTypes) > 0
+}
+
+func (s *subscriber) send(evt resultEvent) {
+	select {
+	case s.events <- evt:
+	case <-s.done:
+	}
+}
+
+// subscribe adds result IDs and job types to the subscriber, and sends events for any of the results that have already completed
+func (r *resultStore) subscribe(sub *subscriber, ids, jobTypes []string) {
+	r.lock.Lock()
+	r.subscribers[sub] = true
+	r.lock.Unlock()
+
+	sub.lock.Lock()
+	for _, id := range ids {
+		sub.ids[id] = true
+	}
+
+	for _, jobType := range jobTypes {
+		sub.jobTypes[jobType] = true
+	}
+	sub.lock.Unlock()
+
+	for _, id := range ids {
+		if record := r.get(id); record != nil && record.isDone() && sub.take(record) {
+			go sub.send(record.event())
+		}
+	}
+}
+
+// unsubscribe removes result IDs and job types from the subscriber
+func (r *resultStore) unsubscribe(sub *subscriber, ids, jobTypes []string) {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	for _, id := range ids {
+		delete(sub.ids, id)
+	}
+
+	for _, jobType := range jobTypes {
+		delete(sub.jobTypes, jobType)
+	}
+}
+
+// removeSubscriber stops sending events to the subscriber
+func (r *resultStore) removeSubscriber(sub *subscriber) {
+	r.lock.Lock()
+	delete(r.subscribers, sub)
+	r.lock.Unlock()
+
+	close(sub.done)
+}
+
+// publish sends the record's event to each subscriber that wants it
+func (r *resultStore) publish(record *resultRecord) {
+	r.lock.Lock()
+	subs := []*subscriber{}
+	for sub := range r.subscribers {
+		if sub.take(record) {
+			subs = append(subs, sub)
+		}
+	}
+	r.lock.Unlock()
+
+	if len(subs) == 0 {
+		return
+	}
+
+	evt := record.event()
+
+	for _, sub := range subs {
+		sub.send(evt)
+	}
+}
+
+// subscriptionFromRequest validates the result IDs and job types in the request's `id` and `jobType` query parameters
+func (s *Server) subscriptionFromRequest(ctx *vk.Ctx, ids, jobTypes []string) error {
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return vk.E(http.StatusBadRequest, fmt.Sprintf("invalid result ID %s", id))
+		}
+
+		record := s.tenantFor(ctx).results.get(id)
+		if record == nil {
+			return vk.E(http.StatusNotFound, fmt.Sprintf("result with ID %s not found", id))
+		} else if record.clientID != clientID(ctx) {
+			return vk.E(http.StatusForbidden, "result belongs to another client")
+		}
+	}
+
+	for _, jobType := range jobTypes {
+		if err := s.authorize(ctx, jobType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// streamHandlerFunc is a handler that writes its own response, for responses that are streamed
+type streamHandlerFunc func(w http.ResponseWriter, r *http.Request, ctx *vk.Ctx) error
+
+// streamHandler adapts a streamHandlerFunc to run after the Server's usual middleware, and the tenantMiddleware
+// if the route is for a Tenant or the rootMiddleware if it isn't. Errors are written in the same format as vk's.
+func (s *Server) streamHandler(forTenant bool, handler streamHandlerFunc) http.HandlerFunc {
+	middleware := []vk.Middleware{s.limitMiddleware(), s.authMiddleware()}
+	if forTenant {
+		middleware = append(middleware, s.tenantMiddleware())
+	} else {
+		middleware = append(middleware, s.rootMiddleware())
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var params httprouter.Params
+
+		// vk doesn't pass the route's params to handlers registered with HandleHTTP, so find the Tenant's name in the path
+		if forTenant {
+			name := strings.SplitN(strings.TrimPrefix(r.URL.Path, tenantRoutePrefix), "/", 2)[0]
+			params = httprouter.Params{{Key: "tenant", Value: name}}
+		}
+
+		ctx := vk.NewCtx(s.log, params, w.Header())
+
+		err := func() error {
+			for _, m := range middleware {
+				if err := m(r, ctx); err != nil {
+					return err
+				}
+			}
+
+			return handler(w, r, ctx)
+		}()
+
+		if err == nil {
+			return
+		}
+
+		status, message := http.StatusInternalServerError, "internal server error"
+		if vkErr, isVKErr := err.(vk.Error); isVKErr {
+			status, message = vkErr.Status(), vkErr.Message()
+		} else {
+			ctx.Log.Error(err)
+		}
+
+		errJSON, _ := json.Marshal(vk.ErrorResponse{StatusCode: status, MessageText: message})
+
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.WriteHeader(status)
+		w.Write(errJSON)
+	}
+}
+
+// sseHandler streams the events of the results and job types in the `id` and `jobType` query parameters
+// as Server-Sent Events. If only result IDs are requested, the stream ends once all of them have been sent.
+func (s *Server) sseHandler() streamHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, ctx *vk.Ctx) error {
+		flusher, canFlush := w.(http.Flusher)
+		if !canFlush {
+			return vk.E(http.StatusInternalServerError, "streaming is not supported")
+		}
+
+		ids, jobTypes := r.URL.Query()["id"], r.URL.Query()["jobType"]
+		if len(ids) == 0 && len(jobTypes) == 0 {
+			return vk.E(http.StatusBadRequest, "at least one id or jobType must be provided")
+		}
+
+		if err := s.subscriptionFromRequest(ctx, ids, jobTypes); err != nil {
+			return err
+		}
+
+		results := s.tenantFor(ctx).results
+
+		sub := newSubscriber(clientID(ctx))
+		defer results.removeSubscriber(sub)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		results.subscribe(sub, ids, jobTypes)
+
+		keepAlive := time.NewTicker(eventKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case evt := <-sub.events:
+				evtJSON, err := json.Marshal(evt)
+				if err != nil {
+					ctx.Log.Error(errors.Wrap(err, "failed to Marshal event"))
+					continue
+				}
+
+				fmt.Fprintf(w, "event: result\nid: %s\ndata: %s\n\n", evt.ID, evtJSON)
+				flusher.Flush()
+
+				if !sub.waiting() && len(sub.events) == 0 {
+					return nil
+				}
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+				flusher.Flush()
+			case <-r.Context().Done():
+				return nil
+			}
+		}
+	}
+}
+
+// wsMessage is sent by WebSocket clients to change their subscriptions, and by the server to report errors
+type wsMessage struct {
+	Action   string   `json:"action,omitempty"`
+	IDs      []string `json:"ids,omitempty"`
+	JobTypes []string `json:"jobTypes,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{}
+
+// wsHandler sends the events of subscribed results and job types over a WebSocket. Subscriptions can be passed using
+// the same query parameters as sseHandler, and changed by sending `subscribe` and `unsubscribe` messages.
+func (s *Server) wsHandler() streamHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, ctx *vk.Ctx) error {
+		ids, jobTypes := r.URL.Query()["id"], r.URL.Query()["jobType"]
+
+		if err := s.subscriptionFromRequest(ctx, ids, jobTypes); err != nil {
+			return err
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade has already responded with an error
+			ctx.Log.Error(errors.Wrap(err, "failed to Upgrade"))
+			return nil
+		}
+
+		defer conn.Close()
+
+		results := s.tenantFor(ctx).results
+
+		sub := newSubscriber(clientID(ctx))
+		defer results.removeSubscriber(sub)
+
+		results.subscribe(sub, ids, jobTypes)
+
+		// only one goroutine can write to the connection at a time
+		writeLock := sync.Mutex{}
+		write := func(msg interface{}) error {
+			writeLock.Lock()
+			defer writeLock.Unlock()
+
+			return conn.WriteJSON(msg)
+		}
+
+		go func() {
+			for {
+				select {
+				case evt := <-sub.events:
+					if err := write(evt); err != nil {
+						return
+					}
+				case <-sub.done:
+					return
+				}
+			}
+		}()
+
+		for {
+			msg := wsMessage{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				// the client has gone away, or sent something that isn't JSON
+				return nil
+			}
+
+			switch msg.Action {
+			case "subscribe":
+				if err := s.subscriptionFromRequest(ctx, msg.IDs, msg.JobTypes); err != nil {
+					write(wsMessage{Error: err.Error()})
+					continue
+				}
+
+				results.subscribe(sub, msg.IDs, msg.JobTypes)
+			case "unsubscribe":
+				results.unsubscribe(sub, msg.IDs, msg.JobTypes)
+			default:
+				write(wsMessage{Error: fmt.Sprintf("unknown action %q", msg.Action)})
+			}
+		}
+	}
+}
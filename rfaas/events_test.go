@@ -0,0 +1,195 @@
+package rfaas
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// scheduleForID schedules a job and returns its result ID
+func scheduleForID(t *testing.T, baseURL, jobType, data string, mods ...func(*http.Request)) string {
+	status, body := doRequest(t, http.MethodPost, baseURL+"/do/"+jobType, []byte(data), mods...)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, string(body))
+	}
+
+	resp := doResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.ResultID
+}
+
+// readSSE reads events from an SSE stream until it ends
+func readSSE(t *testing.T, resp *http.Response) []resultEvent {
+	events := []resultEvent{}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		evt := resultEvent{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &evt); err != nil {
+			t.Fatal(err)
+		}
+
+		events = append(events, evt)
+	}
+
+	return events
+}
+
+func TestSSEResults(t *testing.T) {
+	baseURL := startTestServer(t, nil)
+
+	slowID := scheduleForID(t, baseURL, "echo", "slow")
+	errID := scheduleForID(t, baseURL, "echo", "error")
+
+	resp, err := http.Get(baseURL + "/events?id=" + slowID + "&id=" + errID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// the stream ends once both results have been delivered, in whichever order they complete
+	events := readSSE(t, resp)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+
+	byID := map[string]resultEvent{}
+	for _, evt := range events {
+		byID[evt.ID] = evt
+	}
+
+	if evt := byID[errID]; evt.Status != ResultStatusFailed || evt.Error != "bad" {
+		t.Errorf("unexpected error event %+v", evt)
+	}
+
+	if evt := byID[slowID]; evt.Status != ResultStatusComplete || string(evt.Result) != "slow" || evt.JobType != "echo" {
+		t.Errorf("unexpected slow event %+v", evt)
+	}
+}
+
+func TestSSEErrors(t *testing.T) {
+	baseURL := startTestServer(t, func(s *Server) {
+		s.Configure(
+			UseAuthenticators(NewAPIKeyAuthenticator(map[string]string{"key-a": "a", "key-b": "b"})),
+			UseAuthorizer(Policy{"a": {"echo"}}),
+		)
+	})
+
+	a, b := withHeader(HeaderAPIKey, "key-a"), withHeader(HeaderAPIKey, "key-b")
+
+	id := scheduleForID(t, baseURL, "echo", "hello", a)
+
+	cases := []struct {
+		name   string
+		query  string
+		mods   []func(*http.Request)
+		status int
+	}{
+		{"unauthenticated", "?id=" + id, nil, http.StatusUnauthorized},
+		{"empty", "", []func(*http.Request){a}, http.StatusBadRequest},
+		{"invalid ID", "?id=nope", []func(*http.Request){a}, http.StatusBadRequest},
+		{"unknown ID", "?id=" + "00000000-0000-0000-0000-000000000000", []func(*http.Request){a}, http.StatusNotFound},
+		{"other client's result", "?id=" + id, []func(*http.Request){b}, http.StatusForbidden},
+		{"unauthorized job type", "?jobType=echo", []func(*http.Request){b}, http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		if status, body := doRequest(t, http.MethodGet, baseURL+"/events"+c.query, nil, c.mods...); status != c.status {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.status, status, string(body))
+		}
+	}
+}
+
+func TestWebSocketResults(t *testing.T) {
+	var server *Server
+
+	baseURL := startTestServer(t, func(s *Server) {
+		server = s
+	})
+
+	wsURL := "ws" + strings.TrimPrefix(baseURL, "http") + "/events/ws?jobType=echo"
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+
+	// wait for the subscription to be registered before scheduling
+	for i := 0; i < 50; i++ {
+		server.results.lock.Lock()
+		count := len(server.results.subscribers)
+		server.results.lock.Unlock()
+
+		if count > 0 {
+			break
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	id := scheduleForID(t, baseURL, "echo", "hello")
+
+	evt := resultEvent{}
+	if err := conn.ReadJSON(&evt); err != nil {
+		t.Fatal(err)
+	}
+
+	if evt.ID != id || string(evt.Result) != "hello" {
+		t.Errorf("unexpected event %+v", evt)
+	}
+
+	// unknown actions are reported without closing the connection
+	if err := conn.WriteJSON(wsMessage{Action: "nope"}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := wsMessage{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Error == "" {
+		t.Errorf("expected error message, got %+v", msg)
+	}
+
+	// after unsubscribing from the job type, only the subscribed ID is delivered
+	slowID := scheduleForID(t, baseURL, "echo", "slow")
+
+	if err := conn.WriteJSON(wsMessage{Action: "unsubscribe", JobTypes: []string{"echo"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.WriteJSON(wsMessage{Action: "subscribe", IDs: []string{slowID}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.ReadJSON(&evt); err != nil {
+		t.Fatal(err)
+	}
+
+	if evt.ID != slowID || string(evt.Result) != "slow" {
+		t.Errorf("unexpected event %+v", evt)
+	}
+}
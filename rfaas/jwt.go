@@ -0,0 +1,197 @@
+package rfaas
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// JWTAuthenticator authenticates requests with a JWT bearer token in the Authorization header, verified against
+// a local set of keys. HS256 ([]byte keys), RS256 (*rsa.PublicKey keys), and ES256 (*ecdsa.PublicKey keys) are supported.
+type JWTAuthenticator struct {
+	// Keys maps key IDs (the token's kid header) to keys. If a token has no kid, the key with an empty ID is used.
+	Keys map[string]interface{}
+
+	// Issuer and Audience, if set, must match the token's iss and aud claims
+	Issuer   string
+	Audience string
+
+	// ClientClaim is the claim used as the client ID, sub by default
+	ClientClaim string
+}
+
+// NewJWTAuthenticator creates a JWTAuthenticator that verifies tokens using the provided keys
+func NewJWTAuthenticator(keys map[string]interface{}) *JWTAuthenticator {
+	j := &JWTAuthenticator{
+		Keys:        keys,
+		ClientClaim: "sub",
+	}
+
+	return j
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Authenticate verifies the request's token and returns the client ID from its claims
+func (j *JWTAuthenticator) Authenticate(r *http.Request, _ []byte) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", nil
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.Wrap(ErrInvalidCredentials, "malformed token")
+	}
+
+	header := jwtHeader{}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return "", errors.Wrap(ErrInvalidCredentials, "malformed token header")
+	}
+
+	key, exists := j.Keys[header.Kid]
+	if !exists {
+		return "", errors.Wrapf(ErrInvalidCredentials, "unknown key %q", header.Kid)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.Wrap(ErrInvalidCredentials, "malformed token signature")
+	}
+
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return "", errors.Wrap(ErrInvalidCredentials, err.Error())
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return "", errors.Wrap(ErrInvalidCredentials, "malformed token claims")
+	}
+
+	if err := j.validateClaims(claims); err != nil {
+		return "", errors.Wrap(ErrInvalidCredentials, err.Error())
+	}
+
+	clientClaim := j.ClientClaim
+	if clientClaim == "" {
+		clientClaim = "sub"
+	}
+
+	clientID, _ := claims[clientClaim].(string)
+	if clientID == "" {
+		return "", errors.Wrapf(ErrInvalidCredentials, "token is missing the %s claim", clientClaim)
+	}
+
+	return clientID, nil
+}
+
+func (j *JWTAuthenticator) validateClaims(claims map[string]interface{}) error {
+	now := float64(time.Now().Unix())
+
+	if exp, ok := claims["exp"].(float64); ok && now >= exp {
+		return errors.New("token has expired")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+		return errors.New("token is not valid yet")
+	}
+
+	if j.Issuer != "" && claims["iss"] != j.Issuer {
+		return errors.New("token has the wrong issuer")
+	}
+
+	if j.Audience != "" && !jwtHasAudience(claims["aud"], j.Audience) {
+		return errors.New("token has the wrong audience")
+	}
+
+	return nil
+}
+
+// jwtHasAudience checks the aud claim, which can be a string or an array of strings
+func jwtHasAudience(aud interface{}, audience string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == audience
+	case []interface{}:
+		for _, v := range a {
+			if v == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func verifyJWTSignature(alg string, key interface{}, signed string, signature []byte) error {
+	hash := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return errors.New("key cannot be used for HS256")
+		}
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("signature mismatch")
+		}
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key cannot be used for RS256")
+		}
+
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], signature); err != nil {
+			return errors.New("signature mismatch")
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("key cannot be used for ES256")
+		}
+
+		if len(signature) != 64 {
+			return errors.New("malformed ES256 signature")
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+
+		if !ecdsa.Verify(pub, hash[:], r, s) {
+			return errors.New("signature mismatch")
+		}
+	default:
+		// this includes "none", which must never be accepted
+		return errors.Errorf("unsupported algorithm %q", alg)
+	}
+
+	return nil
+}
+
+func decodeJWTSegment(segment string, target interface{}) error {
+	segmentJSON, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(segmentJSON, target)
+}
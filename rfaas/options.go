@@ -0,0 +1,140 @@
+package rfaas
+
+import "time"
+
+const (
+	defaultResultTTL    = time.Minute * 5
+	defaultMaxBatchSize = 1000
+	defaultMaxBodySize  = 10 << 20
+)
+
+// Options are the options for an rfaas Server, in addition to those of its vk.Server
+type Options struct {
+	// ResultTTL is how long a completed result is kept after the job finishes, allowing it to be fetched
+	// multiple times. If 0 or less, results are removed as soon as they have been fetched once.
+	ResultTTL time.Duration
+
+	// MaxBodySize is the maximum size of a request body in bytes, including bundles uploaded using the admin API.
+	// If 0 or less, there is no limit.
+	MaxBodySize int64
+
+	// MaxBatchSize is the maximum number of jobs in a single batch request. If 0 or less, there is no limit.
+	MaxBatchSize int
+
+	// Authenticators identify the client making each request, tried in order. If there are none, the Server is open to anyone.
+	Authenticators []Authenticator
+
+	// Authorizer decides which job types each client can run. If nil, every authenticated client can run any job type.
+	Authorizer Authorizer
+
+	// AdminClients are the IDs of the authenticated clients allowed to use the admin API
+	AdminClients []string
+
+	// WebhookSecret, if set, is used to sign webhooks (see VerifyWebhook)
+	WebhookSecret []byte
+
+	// WebhookAttempts is the number of times a webhook is attempted before giving up, with
+	// WebhookBackoff as the delay before the first retry, doubling for each one after that
+	WebhookAttempts int
+	WebhookBackoff  time.Duration
+
+	// WebhookStore, if set, persists webhooks until they are delivered, allowing them to be resumed after a restart
+	WebhookStore WebhookStore
+}
+
+// OptionsModifier modifies an rfaas Server's Options
+type OptionsModifier func(*Options)
+
+func defaultOptions() Options {
+	o := Options{
+		ResultTTL:       defaultResultTTL,
+		MaxBodySize:     defaultMaxBodySize,
+		MaxBatchSize:    defaultMaxBatchSize,
+		WebhookAttempts: defaultWebhookAttempts,
+		WebhookBackoff:  defaultWebhookBackoff,
+	}
+
+	return o
+}
+
+// UseResultTTL sets how long completed results are kept
+func UseResultTTL(ttl time.Duration) OptionsModifier {
+	return func(opts *Options) {
+		opts.ResultTTL = ttl
+	}
+}
+
+// UseMaxBodySize sets the maximum size of a request body in bytes
+func UseMaxBodySize(size int64) OptionsModifier {
+	return func(opts *Options) {
+		opts.MaxBodySize = size
+	}
+}
+
+// UseMaxBatchSize sets the maximum number of jobs in a single batch request
+func UseMaxBatchSize(size int) OptionsModifier {
+	return func(opts *Options) {
+		opts.MaxBatchSize = size
+	}
+}
+
+// UseAuthenticators sets the Authenticators used to identify clients
+func UseAuthenticators(authenticators ...Authenticator) OptionsModifier {
+	return func(opts *Options) {
+		opts.Authenticators = authenticators
+	}
+}
+
+// UseAuthorizer sets the Authorizer used to decide which job types each client can run
+func UseAuthorizer(authorizer Authorizer) OptionsModifier {
+	return func(opts *Options) {
+		opts.Authorizer = authorizer
+	}
+}
+
+// UseAdminClients sets the IDs of the clients allowed to use the admin API
+func UseAdminClients(clientIDs ...string) OptionsModifier {
+	return func(opts *Options) {
+		opts.AdminClients = clientIDs
+	}
+}
+
+// UseWebhookSecret sets the secret used to sign webhooks
+func UseWebhookSecret(secret []byte) OptionsModifier {
+	return func(opts *Options) {
+		opts.WebhookSecret = secret
+	}
+}
+
+// UseWebhookRetry sets the number of attempts made to deliver each webhook, and the delay before the first retry
+func UseWebhookRetry(attempts int, backoff time.Duration) OptionsModifier {
+	return func(opts *Options) {
+		opts.WebhookAttempts = attempts
+		opts.WebhookBackoff = backoff
+	}
+}
+
+// UseWebhookStore sets the store used to persist pending webhooks
+func UseWebhookStore(store WebhookStore) OptionsModifier {
+	return func(opts *Options) {
+		opts.WebhookStore = store
+	}
+}
+
+// Configure applies rfaas options to the Server. It should be called before the Server is started.
+func (s *Server) Configure(mods ...OptionsModifier) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, mod := range mods {
+		mod(&s.options)
+	}
+}
+
+// getOptions returns a copy of the Server's options
+func (s *Server) getOptions() Options {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.options
+}
@@ -0,0 +1,234 @@
+package rfaas
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/reactr/rt"
+)
+
+// ResultStatusPending and others are the statuses of a job's result
+const (
+	ResultStatusPending  = "pending"
+	ResultStatusComplete = "complete"
+	ResultStatusFailed   = "failed"
+	ResultStatusCanceled = "canceled"
+)
+
+// ErrResultCanceled is the error of a result that was canceled using the admin API
+var ErrResultCanceled = errors.New("result canceled")
+
+// resultRecord tracks the result of a job scheduled without then=true
+type resultRecord struct {
+	id       string
+	jobType  string
+	clientID string
+	status   string
+	data     interface{}
+	err      error
+	done     chan struct{}
+	lock     sync.RWMutex
+}
+
+// statusResponse describes the state of a result
+type statusResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// complete sets the result's data and error, returning false if it had already been canceled
+func (r *resultRecord) complete(data interface{}, err error) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// the result may have been canceled already
+	if r.status != ResultStatusPending {
+		return false
+	}
+
+	r.data = data
+	r.err = err
+
+	if err != nil {
+		r.status = ResultStatusFailed
+	} else {
+		r.status = ResultStatusComplete
+	}
+
+	close(r.done)
+
+	return true
+}
+
+// cancel completes the result with ErrResultCanceled, returning false if it had already completed
+func (r *resultRecord) cancel() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.status != ResultStatusPending {
+		return false
+	}
+
+	r.err = ErrResultCanceled
+	r.status = ResultStatusCanceled
+
+	close(r.done)
+
+	return true
+}
+
+// wait waits for the result to complete for up to timeout, returning false if it did not.
+// If timeout is 0 or less, it waits forever.
+func (r *resultRecord) wait(timeout time.Duration) bool {
+	if timeout <= 0 {
+		<-r.done
+		return true
+	}
+
+	select {
+	case <-r.done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (r *resultRecord) result() (interface{}, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.data, r.err
+}
+
+func (r *resultRecord) statusResponse() statusResponse {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	resp := statusResponse{
+		ID:     r.id,
+		Status: r.status,
+	}
+
+	if r.err != nil {
+		resp.Error = r.err.Error()
+	}
+
+	return resp
+}
+
+// isDone returns true if the result is no longer pending
+func (r *resultRecord) isDone() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.status != ResultStatusPending
+}
+
+// event returns the resultEvent describing the completed result
+func (r *resultRecord) event() resultEvent {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	evt := resultEvent{
+		ID:      r.id,
+		JobType: r.jobType,
+		Status:  r.status,
+	}
+
+	if r.err != nil {
+		evt.Error = r.err.Error()
+	} else if resultBytes, err := resultToBytes(r.data); err != nil {
+		evt.Status = ResultStatusFailed
+		evt.Error = err.Error()
+	} else {
+		evt.Result = resultBytes
+	}
+
+	return evt
+}
+
+// resultStore holds the results of jobs until they expire, and publishes them to subscribers as they complete
+type resultStore struct {
+	records     map[string]*resultRecord
+	subscribers map[*subscriber]bool
+	lock        sync.Mutex
+}
+
+func newResultStore() *resultStore {
+	r := &resultStore{
+		records:     map[string]*resultRecord{},
+		subscribers: map[*subscriber]bool{},
+		lock:        sync.Mutex{},
+	}
+
+	return r
+}
+
+// add tracks the result of a job of jobType on behalf of clientID, keeping it for ttl once it completes.
+// If ttl is 0 or less, it is kept until it is removed.
+func (r *resultStore) add(res *rt.Result, jobType, clientID string, ttl time.Duration) *resultRecord {
+	record := &resultRecord{
+		id:       res.UUID(),
+		jobType:  jobType,
+		clientID: clientID,
+		status:   ResultStatusPending,
+		done:     make(chan struct{}),
+	}
+
+	r.lock.Lock()
+	r.records[record.id] = record
+	r.lock.Unlock()
+
+	res.ThenDo(func(data interface{}, err error) {
+		if record.complete(data, err) {
+			r.publish(record)
+		}
+
+		if ttl > 0 {
+			time.AfterFunc(ttl, func() {
+				r.remove(record.id)
+			})
+		}
+	})
+
+	return record
+}
+
+func (r *resultStore) get(id string) *resultRecord {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.records[id]
+}
+
+// pending returns the records of results that have not completed, sorted by ID
+func (r *resultStore) pending() []*resultRecord {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	records := []*resultRecord{}
+
+	for _, record := range r.records {
+		record.lock.RLock()
+		if record.status == ResultStatusPending {
+			records = append(records, record)
+		}
+		record.lock.RUnlock()
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].id < records[j].id
+	})
+
+	return records
+}
+
+func (r *resultStore) remove(id string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.records, id)
+}
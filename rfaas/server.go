@@ -1,32 +1,48 @@
 package rfaas
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/suborbital/reactr/rt"
 	"github.com/suborbital/vektor/vk"
 	"github.com/suborbital/vektor/vlog"
 )
 
-var client = &http.Client{}
+// headerMetaPrefix is the prefix of HTTP headers that are mapped to and from job metadata,
+// for example `X-Reactr-Meta-Region: eu` becomes the metadata key `region` with value `eu`
+const headerMetaPrefix = "X-Reactr-Meta-"
 
-func init() {
-	client.Timeout = time.Duration(time.Second * 5)
-}
+// MetaClient and MetaTenant are job metadata keys that are set from the request's authenticated identity.
+// They are reserved, so metadata headers can't set them.
+const (
+	MetaClient = "client"
+	MetaTenant = "tenant"
+)
 
 // Server is a Reactr FaaS server
 type Server struct {
 	*vk.Server
 	*rt.Reactr
-	inFlight map[string]*rt.Result
+	results  *resultStore
+	webhooks *webhookSender
+	tenants  map[string]*Tenant
+	root     *Tenant
+	options  Options
+	log      *vlog.Logger
+
+	// tenantClients maps the ID of each client that belongs to a Tenant to the Tenant's name
+	tenantClients map[string]string
+
+	// bundleLock serializes changes to the bundles of the Server and its Tenants
+	bundleLock sync.Mutex
+
 	sync.Mutex
 }
 
@@ -35,23 +51,91 @@ func New(opts ...vk.OptionsModifier) *Server {
 	r := rt.New()
 	s := vk.New(opts...)
 
+	// vk doesn't expose the logger it was configured with, so find it the same way it does
+	vkOpts := &vk.Options{}
+	for _, mod := range opts {
+		mod(vkOpts)
+	}
+
+	log := vkOpts.Logger
+	if log == nil {
+		log = vlog.Default()
+	}
+
 	server := &Server{
-		Server:   s,
-		Reactr:   r,
-		Mutex:    sync.Mutex{},
-		inFlight: make(map[string]*rt.Result),
+		Server:  s,
+		Reactr:  r,
+		Mutex:   sync.Mutex{},
+		results: newResultStore(),
+		tenants: map[string]*Tenant{},
+		options: defaultOptions(),
+		log:     log,
+
+		tenantClients: map[string]string{},
 	}
 
-	server.POST("/do/:jobtype", server.scheduleHandler())
-	server.GET("/then/:id", server.thenHandler())
+	// requests that aren't for one of the Server's Tenants use its own Reactr and results
+	server.root = &Tenant{reactr: r, results: server.results, bundles: map[string]*bundleInfo{}, bundleOwners: map[string]string{}}
+
+	server.webhooks = newWebhookSender(server, log)
+
+	api := vk.Group("").Before(server.limitMiddleware(), server.authMiddleware(), server.rootMiddleware())
+	api.POST("/do/:jobtype", server.scheduleHandler())
+	api.GET("/then/:id", server.thenHandler())
+	api.GET("/status/:id", server.statusHandler())
+	api.POST("/batch", server.batchHandler())
+
+	tenant := vk.Group(tenantRoutePrefix+":tenant").Before(server.limitMiddleware(), server.authMiddleware(), server.tenantMiddleware())
+	tenant.POST("/do/:jobtype", server.scheduleHandler())
+	tenant.GET("/then/:id", server.thenHandler())
+	tenant.GET("/status/:id", server.statusHandler())
+	tenant.POST("/batch", server.batchHandler())
+
+	server.AddGroup(api)
+	server.AddGroup(tenant)
+	server.AddGroup(server.adminRoutes())
+
+	// streaming responses need the http.ResponseWriter, so these are registered outside of vk's handlers
+	server.HandleHTTP(http.MethodGet, "/events", server.streamHandler(false, server.sseHandler()))
+	server.HandleHTTP(http.MethodGet, "/events/ws", server.streamHandler(false, server.wsHandler()))
+	server.HandleHTTP(http.MethodGet, tenantRoutePrefix+":tenant/events", server.streamHandler(true, server.sseHandler()))
+	server.HandleHTTP(http.MethodGet, tenantRoutePrefix+":tenant/events/ws", server.streamHandler(true, server.wsHandler()))
 
 	return server
 }
 
+// Start resumes any pending webhook deliveries and starts the server
+func (s *Server) Start() error {
+	if err := s.webhooks.resume(); err != nil {
+		s.log.Error(errors.Wrap(err, "failed to resume webhooks"))
+	}
+
+	return s.Server.Start()
+}
+
 type doResponse struct {
 	ResultID string `json:"resultId"`
 }
 
+// jobErrorResponse is the body of the error response for a failed job, using vk's error format
+// with the job's own error in a separate field so that clients don't need to parse the message
+type jobErrorResponse struct {
+	StatusCode  int    `json:"status"`
+	MessageText string `json:"message"`
+	JobError    string `json:"jobError"`
+}
+
+// jobErrorResp responds with a jobErrorResponse for the error returned by a job
+func jobErrorResp(err error) vk.Response {
+	resp := jobErrorResponse{
+		StatusCode:  http.StatusInternalServerError,
+		MessageText: errors.Wrap(err, "job resulted in error").Error(),
+		JobError:    err.Error(),
+	}
+
+	return vk.R(http.StatusInternalServerError, resp)
+}
+
 func (s *Server) scheduleHandler() vk.HandlerFunc {
 	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
 		jobType := ctx.Params.ByName("jobtype")
@@ -59,13 +143,20 @@ func (s *Server) scheduleHandler() vk.HandlerFunc {
 			return nil, vk.E(http.StatusBadRequest, "missing jobtype")
 		}
 
-		data, err := ioutil.ReadAll(r.Body)
+		data, err := readBody(r)
 		if err != nil {
-			return nil, vk.E(http.StatusInternalServerError, "failed to read request body")
+			return nil, err
 		}
-		defer r.Body.Close()
 
-		res := s.Do(rt.NewJob(jobType, data))
+		job := s.jobWithRequestMeta(ctx, rt.NewJob(jobType, data), r.Header)
+
+		if contentType := r.Header.Get("Content-Type"); contentType != "" {
+			job = job.WithMeta(metaContentType, contentType)
+		}
+
+		tenant := s.tenantFor(ctx)
+
+		res := tenant.Do(job)
 
 		callback := r.URL.Query().Get("callback")
 		if callback != "" {
@@ -74,22 +165,27 @@ func (s *Server) scheduleHandler() vk.HandlerFunc {
 				return nil, vk.E(http.StatusBadRequest, errors.Wrap(err, "failed to parse callback URL").Error())
 			}
 
-			res.ThenDo(webhookCallback(callbackURL, ctx.Log))
+			res.ThenDo(s.webhooks.callback(res.UUID(), callbackURL.String()))
 
-			return vk.R(http.StatusOK, nil), nil
+			// the webhook payload includes the result ID, so return it to allow the caller to match them up
+			return doResponse{ResultID: res.UUID()}, nil
 		}
 
 		then := r.URL.Query().Get("then")
 		if then == "true" {
 			result, err := res.Then()
 			if err != nil {
-				return nil, vk.E(http.StatusInternalServerError, errors.Wrap(err, "job resulted in error").Error())
+				return jobErrorResp(err), nil
+			}
+
+			for k, v := range job.Metadata() {
+				ctx.RespHeaders.Set(headerMetaPrefix+k, v)
 			}
 
-			return result, nil
+			return resultResponse(r, ctx, result)
 		}
 
-		s.addInFlight(res)
+		tenant.results.add(res, jobType, clientID(ctx), s.getOptions().ResultTTL)
 
 		resp := doResponse{
 			ResultID: res.UUID(),
@@ -101,81 +197,91 @@ func (s *Server) scheduleHandler() vk.HandlerFunc {
 
 func (s *Server) thenHandler() vk.HandlerFunc {
 	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
-		id := ctx.Params.ByName("id")
-		if len(id) != 24 {
-			return nil, vk.E(http.StatusBadRequest, "invalid result ID")
+		record, err := s.recordForRequest(ctx)
+		if err != nil {
+			return nil, err
 		}
 
-		res := s.getInFlight(id)
-		if res == nil {
-			return nil, vk.E(http.StatusNotFound, fmt.Sprintf("result with ID %s not found", id))
+		// by default, wait for as long as it takes, otherwise respond with the status once the wait is over
+		var wait time.Duration
+		if waitParam := r.URL.Query().Get("wait"); waitParam != "" {
+			wait, err = time.ParseDuration(waitParam)
+			if err != nil || wait <= 0 {
+				return nil, vk.E(http.StatusBadRequest, "invalid wait duration")
+			}
 		}
 
-		defer s.removeInFlight(id)
+		if !record.wait(wait) {
+			return vk.R(http.StatusAccepted, record.statusResponse()), nil
+		}
 
-		result, err := res.Then()
+		// with no TTL, results can only be fetched once
+		if s.getOptions().ResultTTL <= 0 {
+			s.tenantFor(ctx).results.remove(record.id)
+		}
+
+		result, err := record.result()
 		if err != nil {
-			return nil, vk.E(http.StatusInternalServerError, errors.Wrap(err, "job resulted in error").Error())
+			return jobErrorResp(err), nil
 		}
 
-		return result, nil
+		return resultResponse(r, ctx, result)
 	}
 }
 
-func webhookCallback(callbackURL *url.URL, log *vlog.Logger) rt.ResultFunc {
-	return func(res interface{}, err error) {
-		var body []byte
-		var contentType = "application/octet-stream"
-
+func (s *Server) statusHandler() vk.HandlerFunc {
+	return func(r *http.Request, ctx *vk.Ctx) (interface{}, error) {
+		record, err := s.recordForRequest(ctx)
 		if err != nil {
-			body = []byte(errors.Wrap(err, "job_err_result").Error())
-		} else {
-			// if result is bytes, send that
-			if bytes, isBytes := res.([]byte); isBytes {
-				body = bytes
-			} else {
-				// if not, attempt to Marshal it from a struct or error out
-				json, err := json.Marshal(res)
-				if err != nil {
-					body = []byte(errors.Wrap(err, "job_err_result failed to Marshal result").Error())
-				} else {
-					contentType = "application/json"
-					body = json
-				}
-			}
+			return nil, err
 		}
 
-		log.Info("sending callback to", callbackURL.String())
-
-		_, postErr := client.Post(callbackURL.String(), contentType, bytes.NewBuffer(body))
-		if postErr != nil {
-			log.Error(errors.Wrap(postErr, "failed to Post"))
-		}
+		return record.statusResponse(), nil
 	}
 }
 
-func (s *Server) addInFlight(r *rt.Result) {
-	s.Lock()
-	defer s.Unlock()
+// recordForRequest finds the result record for the ID in the request's params
+func (s *Server) recordForRequest(ctx *vk.Ctx) (*resultRecord, error) {
+	id := ctx.Params.ByName("id")
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, vk.E(http.StatusBadRequest, "invalid result ID")
+	}
+
+	record := s.tenantFor(ctx).results.get(id)
+	if record == nil {
+		return nil, vk.E(http.StatusNotFound, fmt.Sprintf("result with ID %s not found", id))
+	}
+
+	// results can only be fetched by the client that scheduled the job
+	if record.clientID != clientID(ctx) {
+		return nil, vk.E(http.StatusForbidden, "result belongs to another client")
+	}
 
-	s.inFlight[r.UUID()] = r
+	return record, nil
 }
 
-func (s *Server) getInFlight(id string) *rt.Result {
-	s.Lock()
-	defer s.Unlock()
+// jobWithRequestMeta adds any metadata headers to the job, along with the client and tenant that scheduled it
+func (s *Server) jobWithRequestMeta(ctx *vk.Ctx, job rt.Job, header http.Header) rt.Job {
+	for k, v := range header {
+		if !strings.HasPrefix(k, headerMetaPrefix) || len(v) == 0 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimPrefix(k, headerMetaPrefix))
+		if key == MetaClient || key == MetaTenant {
+			continue
+		}
 
-	r, ok := s.inFlight[id]
-	if !ok {
-		return nil
+		job = job.WithMeta(key, v[0])
 	}
 
-	return r
-}
+	if id := clientID(ctx); id != "" {
+		job = job.WithMeta(MetaClient, id)
+	}
 
-func (s *Server) removeInFlight(id string) {
-	s.Lock()
-	defer s.Unlock()
+	if name := s.tenantFor(ctx).name; name != "" {
+		job = job.WithMeta(MetaTenant, name)
+	}
 
-	delete(s.inFlight, id)
+	return job
 }
@@ -0,0 +1,218 @@
+package rfaas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/reactr/rt"
+	"github.com/suborbital/vektor/vk"
+	"github.com/suborbital/vektor/vlog"
+)
+
+type echo struct{}
+
+func (e echo) Run(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
+	switch string(job.Bytes()) {
+	case "slow":
+		time.Sleep(time.Millisecond * 500)
+	case "error":
+		return nil, errors.New("bad")
+	}
+
+	return job.Bytes(), nil
+}
+
+func (e echo) OnChange(_ rt.ChangeEvent) error { return nil }
+
+// startTestServer starts a Server on a free port and returns its base URL
+func startTestServer(t *testing.T, setup func(*Server)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	logger := vlog.Default(vlog.Level(vlog.LogLevelError))
+
+	server := New(vk.UseInsecureHTTP(port), vk.UseLogger(logger))
+	server.Handle("echo", echo{})
+
+	if setup != nil {
+		setup(server)
+	}
+
+	go server.Start()
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	// wait for the server to start listening
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port)); err == nil {
+			conn.Close()
+			return baseURL
+		}
+
+		time.Sleep(time.Millisecond * 20)
+	}
+
+	t.Fatal("server did not start")
+
+	return ""
+}
+
+func doRequest(t *testing.T, method, url string, body []byte, mods ...func(*http.Request)) (int, []byte) {
+	status, _, respBody := doRequestWithHeaders(t, method, url, body, mods...)
+
+	return status, respBody
+}
+
+func doRequestWithHeaders(t *testing.T, method, url string, body []byte, mods ...func(*http.Request)) (int, http.Header, []byte) {
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mod := range mods {
+		mod(req)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, resp.Header, respBody
+}
+
+func scheduleJob(t *testing.T, baseURL string, body string) string {
+	status, respBody := doRequest(t, http.MethodPost, baseURL+"/do/echo", []byte(body))
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, string(respBody))
+	}
+
+	resp := doResponse{}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.ResultID
+}
+
+func TestThen(t *testing.T) {
+	baseURL := startTestServer(t, nil)
+
+	id := scheduleJob(t, baseURL, "hello")
+
+	// results are retained, so they can be fetched more than once
+	for i := 0; i < 2; i++ {
+		status, body := doRequest(t, http.MethodGet, baseURL+"/then/"+id, nil)
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", status, string(body))
+		}
+
+		if string(body) != "hello" {
+			t.Errorf("expected 'hello', got %q", string(body))
+		}
+	}
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/then/not-a-uuid", nil); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid ID, got %d", status)
+	}
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/then/"+rt.NewJob("", nil).UUID(), nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown ID, got %d", status)
+	}
+}
+
+func TestThenWait(t *testing.T) {
+	baseURL := startTestServer(t, nil)
+
+	id := scheduleJob(t, baseURL, "slow")
+
+	status, body := doRequest(t, http.MethodGet, baseURL+"/then/"+id+"?wait=50ms", nil)
+	if status != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", status, string(body))
+	}
+
+	resp := statusResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Status != ResultStatusPending {
+		t.Errorf("expected pending, got %s", resp.Status)
+	}
+
+	status, body = doRequest(t, http.MethodGet, baseURL+"/then/"+id+"?wait=5s", nil)
+	if status != http.StatusOK || string(body) != "slow" {
+		t.Errorf("expected 200 'slow', got %d %q", status, string(body))
+	}
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/then/"+id+"?wait=soon", nil); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid wait, got %d", status)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	baseURL := startTestServer(t, nil)
+
+	checkStatus := func(id, expected string) {
+		status, body := doRequest(t, http.MethodGet, baseURL+"/status/"+id, nil)
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", status, string(body))
+		}
+
+		resp := statusResponse{}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Status != expected {
+			t.Errorf("expected status %s, got %s", expected, resp.Status)
+		}
+	}
+
+	slowID := scheduleJob(t, baseURL, "slow")
+	checkStatus(slowID, ResultStatusPending)
+
+	errID := scheduleJob(t, baseURL, "error")
+	doRequest(t, http.MethodGet, baseURL+"/then/"+errID, nil)
+	checkStatus(errID, ResultStatusFailed)
+
+	doRequest(t, http.MethodGet, baseURL+"/then/"+slowID, nil)
+	checkStatus(slowID, ResultStatusComplete)
+}
+
+func TestResultTTL(t *testing.T) {
+	baseURL := startTestServer(t, func(s *Server) {
+		s.Configure(UseResultTTL(time.Millisecond * 100))
+	})
+
+	id := scheduleJob(t, baseURL, "hello")
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/then/"+id, nil); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	time.Sleep(time.Millisecond * 300)
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/status/"+id, nil); status != http.StatusNotFound {
+		t.Errorf("expected 404 after TTL, got %d", status)
+	}
+}
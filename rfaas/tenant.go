@@ -0,0 +1,371 @@
+package rfaas
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/grav/grav"
+	"github.com/suborbital/reactr/rt"
+	"github.com/suborbital/vektor/vk"
+)
+
+// ctxKeyTenant is the vk.Ctx key of the Tenant that a request is for
+const ctxKeyTenant = "rfaas.tenant"
+
+// tenantRoutePrefix is the prefix of the routes for each Tenant, followed by the Tenant's name
+const tenantRoutePrefix = "/t/"
+
+// ErrTenantExists is returned when adding a Tenant whose name is already in use
+var ErrTenantExists = errors.New("tenant already exists")
+
+// TenantOptions are the options for a Tenant
+type TenantOptions struct {
+	// Clients are the IDs of the authenticated clients that belong to the Tenant, and so can use its routes
+	Clients []string
+
+	// MaxConcurrency is the maximum number of the Tenant's jobs that run at once, with any others waiting
+	// for one of them to finish. If 0 or less, there is no limit beyond each handler's PoolSize and ResourcePool.
+	MaxConcurrency int
+
+	// RequestRate is the number of requests per second that the Tenant's clients can make between them,
+	// allowing bursts of up to RequestBurst requests. If 0 or less, there is no limit.
+	RequestRate  float64
+	RequestBurst int
+}
+
+// TenantOptionsModifier modifies a Tenant's Options
+type TenantOptionsModifier func(*TenantOptions)
+
+// UseTenantClients sets the IDs of the clients that belong to the Tenant
+func UseTenantClients(ids ...string) TenantOptionsModifier {
+	return func(opts *TenantOptions) {
+		opts.Clients = ids
+	}
+}
+
+// UseTenantConcurrency sets the maximum number of the Tenant's jobs that run at once
+func UseTenantConcurrency(max int) TenantOptionsModifier {
+	return func(opts *TenantOptions) {
+		opts.MaxConcurrency = max
+	}
+}
+
+// UseTenantRequestRate sets the number of requests per second the Tenant's clients can make, and the size of bursts above that rate
+func UseTenantRequestRate(perSecond float64, burst int) TenantOptionsModifier {
+	return func(opts *TenantOptions) {
+		opts.RequestRate = perSecond
+		opts.RequestBurst = burst
+	}
+}
+
+// Tenant is an isolated set of handlers within a Server, served under /t/{name}/. Each Tenant has its own
+// Reactr, and so its own handlers, caches, and results, and can only be used by the clients that belong to it.
+// The Reactr is only available through the Tenant's methods, so that its MaxConcurrency applies to every handler.
+type Tenant struct {
+	reactr      *rt.Reactr
+	name        string
+	results     *resultStore
+	bundles     map[string]*bundleInfo
+	options     TenantOptions
+	limiter     *rateLimiter
+	concurrency *concurrencyLimiter
+
+	// bundleOwners maps each job type handled by one of the Tenant's bundles to the identifier of the bundle
+	bundleOwners map[string]string
+}
+
+// AddTenant adds a Tenant to the Server. Tenants require authentication to be configured, since
+// their clients are identified by the Server's authenticators.
+func (s *Server) AddTenant(name string, mods ...TenantOptionsModifier) (*Tenant, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return nil, errors.Errorf("invalid tenant name %q", name)
+	}
+
+	options := TenantOptions{}
+	for _, mod := range mods {
+		mod(&options)
+	}
+
+	t := &Tenant{
+		reactr:  rt.New(),
+		name:    name,
+		results: newResultStore(),
+		bundles: map[string]*bundleInfo{},
+		options: options,
+
+		bundleOwners: map[string]string{},
+	}
+
+	if options.RequestRate > 0 {
+		t.limiter = newRateLimiter(options.RequestRate, options.RequestBurst)
+	}
+
+	if options.MaxConcurrency > 0 {
+		t.concurrency = newConcurrencyLimiter(options.MaxConcurrency)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.tenants[name]; exists {
+		return nil, errors.Wrap(ErrTenantExists, name)
+	}
+
+	// each client can only belong to one Tenant, so that its requests can't reach any other
+	for _, id := range options.Clients {
+		if other, exists := s.tenantClients[id]; exists {
+			return nil, errors.Errorf("client %s already belongs to tenant %s", id, other)
+		}
+	}
+
+	for _, id := range options.Clients {
+		s.tenantClients[id] = name
+	}
+
+	s.tenants[name] = t
+
+	return t, nil
+}
+
+// Tenant returns the Tenant with the given name, or nil if there is none
+func (s *Server) Tenant(name string) *Tenant {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.tenants[name]
+}
+
+// Tenants returns the names of the Server's Tenants, sorted
+func (s *Server) Tenants() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	names := make([]string, 0, len(s.tenants))
+	for name := range s.tenants {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Name returns the Tenant's name
+func (t *Tenant) Name() string {
+	return t.name
+}
+
+// Handle registers a Runnable for the Tenant, applying the Tenant's MaxConcurrency
+func (t *Tenant) Handle(jobType string, runner rt.Runnable, options ...rt.Option) rt.JobFunc {
+	return t.reactr.Handle(jobType, runner, t.handlerOptions(options)...)
+}
+
+// HandleMsg registers a Runnable for the Tenant that is run for each message of msgType received by the pod,
+// applying the Tenant's MaxConcurrency
+func (t *Tenant) HandleMsg(pod *grav.Pod, msgType string, runner rt.Runnable, options ...rt.Option) {
+	t.reactr.HandleMsg(pod, msgType, runner, t.handlerOptions(options)...)
+}
+
+// Replace replaces the Runnable of one of the Tenant's handlers, applying the Tenant's MaxConcurrency
+func (t *Tenant) Replace(jobType string, runner rt.Runnable, options ...rt.Option) error {
+	return t.reactr.Replace(jobType, runner, t.handlerOptions(options)...)
+}
+
+// Unhandle removes one of the Tenant's handlers
+func (t *Tenant) Unhandle(jobType string) error {
+	return t.reactr.Unhandle(jobType)
+}
+
+// Do schedules a job to be run by one of the Tenant's handlers
+func (t *Tenant) Do(job rt.Job) *rt.Result {
+	return t.reactr.Do(job)
+}
+
+// Schedule adds a Schedule to the Tenant's Reactr
+func (t *Tenant) Schedule(s rt.Schedule) {
+	t.reactr.Schedule(s)
+}
+
+// Use adds global Middleware to every one of the Tenant's handlers
+func (t *Tenant) Use(middleware ...rt.Middleware) {
+	t.reactr.Use(middleware...)
+}
+
+// Handlers returns information about each of the Tenant's handlers
+func (t *Tenant) Handlers() []rt.HandlerInfo {
+	return t.reactr.Handlers()
+}
+
+// Schedules returns information about each of the Tenant's schedules
+func (t *Tenant) Schedules() []rt.ScheduleInfo {
+	return t.reactr.Schedules()
+}
+
+// handlerOptions applies the Tenant's concurrency limit to a handler's options. The limit is separate from any
+// ResourcePool the handler is bound to, so that both apply.
+func (t *Tenant) handlerOptions(options []rt.Option) []rt.Option {
+	if t.concurrency == nil {
+		return options
+	}
+
+	// the limit comes first so that it is the outermost of the handler's middleware
+	return append([]rt.Option{rt.UseMiddleware(t.concurrency.middleware())}, options...)
+}
+
+// clientTenant returns the name of the Tenant the client belongs to, or an empty string if there is none
+func (s *Server) clientTenant(clientID string) string {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.tenantClients[clientID]
+}
+
+// tenantMiddleware finds the Tenant named in the request's params, ensures the client belongs to it,
+// and applies its request rate. It must run after authMiddleware.
+func (s *Server) tenantMiddleware() vk.Middleware {
+	return func(r *http.Request, ctx *vk.Ctx) error {
+		if len(s.getOptions().Authenticators) == 0 {
+			return vk.E(http.StatusForbidden, "tenants require authentication to be configured")
+		}
+
+		name := ctx.Params.ByName("tenant")
+
+		// the same response is used for tenants that don't exist, so as not to reveal which do
+		t := s.Tenant(name)
+		if t == nil || s.clientTenant(clientID(ctx)) != name {
+			return vk.E(http.StatusForbidden, fmt.Sprintf("client does not belong to tenant %s", name))
+		}
+
+		if t.limiter != nil {
+			if allowed, wait := t.limiter.allow(); !allowed {
+				ctx.RespHeaders.Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				return vk.E(http.StatusTooManyRequests, fmt.Sprintf("tenant %s has exceeded its request rate", name))
+			}
+		}
+
+		ctx.Set(ctxKeyTenant, t)
+
+		return nil
+	}
+}
+
+// rootMiddleware rejects requests to the Server's own routes from clients that belong to a Tenant,
+// which must use their Tenant's routes instead. It must run after authMiddleware.
+func (s *Server) rootMiddleware() vk.Middleware {
+	return func(r *http.Request, ctx *vk.Ctx) error {
+		if len(s.getOptions().Authenticators) == 0 {
+			return nil
+		}
+
+		if name := s.clientTenant(clientID(ctx)); name != "" {
+			return vk.E(http.StatusForbidden, fmt.Sprintf("client belongs to tenant %s, and must use its routes under %s%s/", name, tenantRoutePrefix, name))
+		}
+
+		return nil
+	}
+}
+
+// tenantFor returns the Tenant that the request is for, which is the Server's own if it isn't for one of its Tenants
+func (s *Server) tenantFor(ctx *vk.Ctx) *Tenant {
+	if t, isTenant := ctx.Get(ctxKeyTenant).(*Tenant); isTenant {
+		return t
+	}
+
+	return s.root
+}
+
+// rateLimiter is a token bucket allowing rate requests per second, with bursts of up to burst requests
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	lock   sync.Mutex
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	r := &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		lock:   sync.Mutex{},
+	}
+
+	return r
+}
+
+// allow takes a token if one is available, and otherwise returns how long it will be until one is
+func (r *rateLimiter) allow() (bool, time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+
+	r.tokens = math.Min(r.burst, r.tokens+now.Sub(r.last).Seconds()*r.rate)
+	r.last = now
+
+	if r.tokens < 1 {
+		return false, time.Duration((1 - r.tokens) / r.rate * float64(time.Second))
+	}
+
+	r.tokens--
+
+	return true, 0
+}
+
+// concurrencyLimiter bounds the number of jobs that run at once across every handler it is applied to
+type concurrencyLimiter struct {
+	slots chan struct{}
+}
+
+func newConcurrencyLimiter(max int) *concurrencyLimiter {
+	c := &concurrencyLimiter{
+		slots: make(chan struct{}, max),
+	}
+
+	return c
+}
+
+// middleware returns an rt.Middleware that holds a slot while the job runs, giving up if the job's deadline
+// passes while it is waiting so that a job that has already failed is never run
+func (c *concurrencyLimiter) middleware() rt.Middleware {
+	return func(next rt.RunFunc) rt.RunFunc {
+		return func(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
+			deadline, hasDeadline := ctx.Deadline()
+
+			var expired <-chan time.Time
+			if hasDeadline {
+				timer := time.NewTimer(time.Until(deadline))
+				defer timer.Stop()
+
+				expired = timer.C
+			}
+
+			select {
+			case c.slots <- struct{}{}:
+			case <-expired:
+				return nil, rt.ErrJobDeadlineExceeded
+			}
+
+			defer func() { <-c.slots }()
+
+			if hasDeadline && !time.Now().Before(deadline) {
+				return nil, rt.ErrJobDeadlineExceeded
+			}
+
+			return next(job, ctx)
+		}
+	}
+}
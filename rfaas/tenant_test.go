@@ -0,0 +1,286 @@
+package rfaas
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/suborbital/reactr/rt"
+)
+
+// cacher sets the cache key "key" to its data, or gets it if its data is "get"
+type cacher struct{}
+
+func (c cacher) Run(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
+	if string(job.Bytes()) == "get" {
+		return ctx.Cache.Get("key")
+	}
+
+	return nil, ctx.Cache.Set("key", job.Bytes(), 0)
+}
+
+func (c cacher) OnChange(_ rt.ChangeEvent) error { return nil }
+
+// counter records the most jobs it has run at once
+type counter struct {
+	running int32
+	max     int32
+}
+
+func (c *counter) Run(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
+	running := atomic.AddInt32(&c.running, 1)
+	defer atomic.AddInt32(&c.running, -1)
+
+	for {
+		max := atomic.LoadInt32(&c.max)
+		if running <= max || atomic.CompareAndSwapInt32(&c.max, max, running) {
+			break
+		}
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	return nil, nil
+}
+
+func (c *counter) OnChange(_ rt.ChangeEvent) error { return nil }
+
+func startTenantTestServer(t *testing.T, setup func(*Server)) string {
+	return startTestServer(t, func(s *Server) {
+		s.Configure(UseAuthenticators(NewAPIKeyAuthenticator(map[string]string{"key-a": "a", "key-b": "b"})))
+
+		if setup != nil {
+			setup(s)
+		}
+	})
+}
+
+func addTenant(t *testing.T, s *Server, name string, mods ...TenantOptionsModifier) *Tenant {
+	tenant, err := s.AddTenant(name, mods...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tenant
+}
+
+func TestTenantIsolation(t *testing.T) {
+	var server *Server
+
+	baseURL := startTenantTestServer(t, func(s *Server) {
+		server = s
+
+		alpha := addTenant(t, s, "alpha", UseTenantClients("a"))
+		alpha.Handle("cache", cacher{}, rt.CacheNamespace("shared"))
+
+		beta := addTenant(t, s, "beta", UseTenantClients("b"))
+		beta.Handle("cache", cacher{}, rt.CacheNamespace("shared"))
+	})
+
+	a, b := withHeader(HeaderAPIKey, "key-a"), withHeader(HeaderAPIKey, "key-b")
+
+	if _, err := server.AddTenant("alpha"); err == nil {
+		t.Error("expected error adding duplicate tenant")
+	}
+
+	if names := server.Tenants(); len(names) != 2 || names[0] != "alpha" || names[1] != "beta" {
+		t.Errorf("unexpected tenants %v", names)
+	}
+
+	// clients can only use the tenants they belong to, and unknown tenants look the same
+	for _, path := range []string{"/t/alpha/do/cache?then=true", "/t/gamma/do/cache?then=true"} {
+		if status, _ := doRequest(t, http.MethodPost, baseURL+path, []byte("get"), b); status != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", path, status)
+		}
+	}
+
+	// tenants' handlers are separate from the server's and each other's, as are their caches
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/t/alpha/do/echo?then=true", []byte("hi"), a); status != http.StatusInternalServerError {
+		t.Errorf("expected server's echo handler to be unavailable to tenant, got %d", status)
+	}
+
+	if status, body := doRequest(t, http.MethodPost, baseURL+"/t/alpha/do/cache?then=true", []byte("alpha"), a); status != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", status, string(body))
+	}
+
+	if status, body := doRequest(t, http.MethodPost, baseURL+"/t/alpha/do/cache?then=true", []byte("get"), a); status != http.StatusOK || string(body) != "alpha" {
+		t.Errorf("expected cached value, got %d: %s", status, string(body))
+	}
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/t/beta/do/cache?then=true", []byte("get"), b); status != http.StatusInternalServerError {
+		t.Errorf("expected beta's cache to be empty, got %d", status)
+	}
+
+	// results are only available from the tenant that scheduled them
+	id := scheduleForID(t, baseURL+"/t/alpha", "cache", "get", a)
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/t/beta/then/"+id, nil, b); status != http.StatusNotFound {
+		t.Errorf("expected 404 from another tenant's results, got %d", status)
+	}
+
+	if status, body := doRequest(t, http.MethodGet, baseURL+"/t/alpha/then/"+id, nil, a); status != http.StatusOK || string(body) != "alpha" {
+		t.Errorf("expected result, got %d: %s", status, string(body))
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, baseURL+"/t/alpha/events?id="+id, nil)
+	a(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if events := readSSE(t, resp); len(events) != 1 || events[0].ID != id {
+		t.Errorf("expected tenant's event, got %+v", events)
+	}
+
+	if status, _ := doRequest(t, http.MethodGet, baseURL+"/t/beta/events?id="+id, nil, b); status != http.StatusNotFound {
+		t.Errorf("expected 404 from another tenant's events, got %d", status)
+	}
+}
+
+func TestTenantRequiresAuth(t *testing.T) {
+	baseURL := startTestServer(t, func(s *Server) {
+		addTenant(t, s, "alpha", UseTenantClients(""))
+	})
+
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/t/alpha/do/echo", []byte("hi")); status != http.StatusForbidden {
+		t.Errorf("expected 403 without authentication configured, got %d", status)
+	}
+}
+
+func TestTenantRequestRate(t *testing.T) {
+	baseURL := startTenantTestServer(t, func(s *Server) {
+		alpha := addTenant(t, s, "alpha", UseTenantClients("a"), UseTenantRequestRate(1, 2))
+		alpha.Handle("echo", echo{})
+	})
+
+	a := withHeader(HeaderAPIKey, "key-a")
+
+	for i := 0; i < 2; i++ {
+		if status, _ := doRequest(t, http.MethodPost, baseURL+"/t/alpha/do/echo?then=true", []byte("hi"), a); status != http.StatusOK {
+			t.Errorf("expected request %d within burst to succeed, got %d", i, status)
+		}
+	}
+
+	status, headers, _ := doRequestWithHeaders(t, http.MethodPost, baseURL+"/t/alpha/do/echo?then=true", []byte("hi"), a)
+	if status != http.StatusTooManyRequests || headers.Get("Retry-After") != "1" {
+		t.Errorf("expected 429 with Retry-After, got %d %q", status, headers.Get("Retry-After"))
+	}
+
+}
+
+func TestTenantClientsRejectedFromRoot(t *testing.T) {
+	var server *Server
+
+	baseURL := startTenantTestServer(t, func(s *Server) {
+		server = s
+		addTenant(t, s, "alpha", UseTenantClients("a"))
+	})
+
+	a, b := withHeader(HeaderAPIKey, "key-a"), withHeader(HeaderAPIKey, "key-b")
+
+	// clients that belong to a tenant can't use the server's own handlers, results, or events
+	for _, path := range []string{"/do/echo?then=true", "/batch", "/events?jobType=echo"} {
+		method := http.MethodPost
+		if strings.HasPrefix(path, "/events") {
+			method = http.MethodGet
+		}
+
+		if status, _ := doRequest(t, method, baseURL+path, []byte(`[{"jobType":"echo"}]`), a); status != http.StatusForbidden {
+			t.Errorf("%s: expected 403, got %d", path, status)
+		}
+	}
+
+	// other clients can
+	if status, _ := doRequest(t, http.MethodPost, baseURL+"/do/echo?then=true", []byte("hi"), b); status != http.StatusOK {
+		t.Errorf("expected 200, got %d", status)
+	}
+
+	if _, err := server.AddTenant("beta", UseTenantClients("a")); err == nil {
+		t.Error("expected error adding a client to a second tenant")
+	}
+}
+
+func TestTenantConcurrency(t *testing.T) {
+	count := &counter{}
+
+	var alpha *Tenant
+
+	baseURL := startTenantTestServer(t, func(s *Server) {
+		alpha = addTenant(t, s, "alpha", UseTenantClients("a"), UseTenantConcurrency(2))
+		alpha.Handle("one", count, rt.PoolSize(3))
+		alpha.Handle("two", count, rt.PoolSize(3), rt.ResourcePool("db", 3))
+	})
+
+	a := withHeader(HeaderAPIKey, "key-a")
+
+	// the limit applies to all of the tenant's handlers together
+	status, body := doRequest(t, http.MethodPost, baseURL+"/t/alpha/batch?then=true", []byte(`[
+		{"jobType":"one"},{"jobType":"one"},{"jobType":"one"},
+		{"jobType":"two"},{"jobType":"two"},{"jobType":"two"}
+	]`), a)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, string(body))
+	}
+
+	if max := atomic.LoadInt32(&count.max); max != 2 {
+		t.Errorf("expected 2 jobs at once, got %d", max)
+	}
+
+	// the tenant's limit is applied alongside the handler's own resource pool rather than replacing it
+	for _, info := range alpha.Handlers() {
+		if info.JobType == "two" && info.ResourcePool != "db" {
+			t.Errorf("expected two to keep its resource pool, got %q", info.ResourcePool)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(20, 1)
+
+	if allowed, _ := limiter.allow(); !allowed {
+		t.Error("expected first request to be allowed")
+	}
+
+	allowed, wait := limiter.allow()
+	if allowed || wait <= 0 || wait > time.Millisecond*50 {
+		t.Errorf("expected to wait up to 50ms, got %v %s", allowed, wait)
+	}
+
+	time.Sleep(wait)
+
+	if allowed, _ := limiter.allow(); !allowed {
+		t.Error("expected request to be allowed after waiting")
+	}
+}
+
+func TestTenantReservedMeta(t *testing.T) {
+	baseURL := startTenantTestServer(t, func(s *Server) {
+		alpha := addTenant(t, s, "alpha", UseTenantClients("a"))
+		alpha.Handle("echo", echo{})
+	})
+
+	mods := []func(*http.Request){
+		withHeader(HeaderAPIKey, "key-a"),
+		withHeader(headerMetaPrefix+"Tenant", "beta"),
+		withHeader(headerMetaPrefix+"Client", "b"),
+		withHeader(headerMetaPrefix+"Region", "eu"),
+	}
+
+	// the reserved keys are set from the client's identity rather than its headers
+	status, headers, _ := doRequestWithHeaders(t, http.MethodPost, baseURL+"/t/alpha/do/echo?then=true", []byte("hi"), mods...)
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	for key, val := range map[string]string{"Tenant": "alpha", "Client": "a", "Region": "eu"} {
+		if got := headers.Get(headerMetaPrefix + key); got != val {
+			t.Errorf("expected %s metadata %q, got %q", key, val, got)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package rfaas
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/suborbital/reactr/rt"
+	"github.com/suborbital/vektor/vlog"
+)
+
+// HeaderDelivery is the HTTP header containing the unique ID of a webhook delivery, which is the same for every attempt
+const HeaderDelivery = "X-Reactr-Delivery"
+
+const (
+	defaultWebhookAttempts = 5
+	defaultWebhookBackoff  = time.Second
+)
+
+// ErrWebhookSignature is returned by VerifyWebhook when a webhook's signature is missing or invalid
+var ErrWebhookSignature = errors.New("invalid webhook signature")
+
+// WebhookPayload is the body of the webhook sent to a job's callback URL when it completes
+type WebhookPayload struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Result []byte `json:"result,omitempty"`
+}
+
+// WebhookDelivery is a webhook waiting to be delivered
+type WebhookDelivery struct {
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	Body     []byte `json:"body"`
+	Attempts int    `json:"attempts"`
+}
+
+// WebhookStore persists webhook deliveries until they succeed or run out of attempts
+type WebhookStore interface {
+	Save(delivery WebhookDelivery) error
+	Delete(id string) error
+	Pending() ([]WebhookDelivery, error)
+}
+
+// VerifyWebhook verifies the signature of a webhook request sent by a Server configured with the same secret,
+// and returns its payload. Webhooks are signed in the same way as requests authenticated by HMACAuthenticator.
+func VerifyWebhook(r *http.Request, secret []byte) (*WebhookPayload, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to ReadAll")
+	}
+
+	timestamp := r.Header.Get(HeaderTimestamp)
+
+	unixTime, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrWebhookSignature
+	}
+
+	if skew := time.Since(time.Unix(unixTime, 0)); skew > maxSignatureSkew || skew < -maxSignatureSkew {
+		return nil, ErrWebhookSignature
+	}
+
+	expected := requestSignature(secret, timestamp, r.Method, r.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get(HeaderSignature))) {
+		return nil, ErrWebhookSignature
+	}
+
+	payload := &WebhookPayload{}
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, errors.Wrap(err, "failed to Unmarshal")
+	}
+
+	return payload, nil
+}
+
+// webhookSender delivers webhooks, retrying with exponential backoff
+type webhookSender struct {
+	server *Server
+	client *http.Client
+	log    *vlog.Logger
+}
+
+func newWebhookSender(server *Server, log *vlog.Logger) *webhookSender {
+	w := &webhookSender{
+		server: server,
+		client: &http.Client{Timeout: time.Second * 5},
+		log:    log,
+	}
+
+	return w
+}
+
+// callback returns a ResultFunc that sends the job's result to callbackURL
+func (w *webhookSender) callback(jobUUID, callbackURL string) rt.ResultFunc {
+	return func(res interface{}, err error) {
+		payload := WebhookPayload{
+			ID:     jobUUID,
+			Status: ResultStatusComplete,
+		}
+
+		if err != nil {
+			payload.Status = ResultStatusFailed
+			payload.Error = err.Error()
+		} else if resultBytes, err := resultToBytes(res); err != nil {
+			payload.Status = ResultStatusFailed
+			payload.Error = err.Error()
+		} else {
+			payload.Result = resultBytes
+		}
+
+		body, err := json.Marshal(payload)
+		if err != nil {
+			w.log.Error(errors.Wrap(err, "failed to Marshal webhook payload"))
+			return
+		}
+
+		delivery := WebhookDelivery{
+			ID:   uuid.New().String(),
+			URL:  callbackURL,
+			Body: body,
+		}
+
+		if store := w.server.getOptions().WebhookStore; store != nil {
+			if err := store.Save(delivery); err != nil {
+				w.log.Error(errors.Wrap(err, "failed to Save webhook delivery"))
+			}
+		}
+
+		w.deliver(delivery)
+	}
+}
+
+// resume delivers any webhooks that were pending when the Server last stopped
+func (w *webhookSender) resume() error {
+	store := w.server.getOptions().WebhookStore
+	if store == nil {
+		return nil
+	}
+
+	pending, err := store.Pending()
+	if err != nil {
+		return errors.Wrap(err, "failed to load pending webhooks")
+	}
+
+	for i := range pending {
+		go w.deliver(pending[i])
+	}
+
+	return nil
+}
+
+// deliver sends the webhook until it succeeds or runs out of attempts
+func (w *webhookSender) deliver(delivery WebhookDelivery) {
+	opts := w.server.getOptions()
+
+	for delivery.Attempts < opts.WebhookAttempts {
+		if delivery.Attempts > 0 {
+			time.Sleep(opts.WebhookBackoff * time.Duration(1<<uint(delivery.Attempts-1)))
+		}
+
+		w.log.Info("sending callback to", delivery.URL)
+
+		err := w.send(delivery, opts.WebhookSecret)
+
+		delivery.Attempts++
+
+		if err == nil {
+			break
+		}
+
+		w.log.Error(errors.Wrapf(err, "webhook attempt %d of %d failed", delivery.Attempts, opts.WebhookAttempts))
+
+		if opts.WebhookStore != nil && delivery.Attempts < opts.WebhookAttempts {
+			if err := opts.WebhookStore.Save(delivery); err != nil {
+				w.log.Error(errors.Wrap(err, "failed to Save webhook delivery"))
+			}
+		}
+	}
+
+	if opts.WebhookStore != nil {
+		if err := opts.WebhookStore.Delete(delivery.ID); err != nil {
+			w.log.Error(errors.Wrap(err, "failed to Delete webhook delivery"))
+		}
+	}
+}
+
+// send makes a single delivery attempt, returning an error for network errors and non-2xx responses
+func (w *webhookSender) send(delivery WebhookDelivery, secret []byte) error {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewBuffer(delivery.Body))
+	if err != nil {
+		return errors.Wrap(err, "failed to NewRequest")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDelivery, delivery.ID)
+
+	if len(secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+		req.Header.Set(HeaderTimestamp, timestamp)
+		req.Header.Set(HeaderSignature, requestSignature(secret, timestamp, req.Method, req.URL.RequestURI(), delivery.Body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to Do")
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// FileWebhookStore is a WebhookStore that keeps each pending delivery as a JSON file in a directory
+type FileWebhookStore struct {
+	dir  string
+	lock sync.Mutex
+}
+
+// NewFileWebhookStore creates a FileWebhookStore in dir, creating the directory if needed
+func NewFileWebhookStore(dir string) (*FileWebhookStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to MkdirAll")
+	}
+
+	f := &FileWebhookStore{
+		dir:  dir,
+		lock: sync.Mutex{},
+	}
+
+	return f, nil
+}
+
+// Save writes the delivery to disk, replacing any earlier version of it
+func (f *FileWebhookStore) Save(delivery WebhookDelivery) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	deliveryJSON, err := json.Marshal(delivery)
+	if err != nil {
+		return errors.Wrap(err, "failed to Marshal")
+	}
+
+	// write to a temporary file first so that a crash can't leave a partially written delivery behind
+	tmpPath := f.path(delivery.ID) + ".tmp"
+
+	if err := ioutil.WriteFile(tmpPath, deliveryJSON, 0600); err != nil {
+		return errors.Wrap(err, "failed to WriteFile")
+	}
+
+	return os.Rename(tmpPath, f.path(delivery.ID))
+}
+
+// Delete removes the delivery from disk
+func (f *FileWebhookStore) Delete(id string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if err := os.Remove(f.path(id)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to Remove")
+	}
+
+	return nil
+}
+
+// Pending returns all of the deliveries on disk
+func (f *FileWebhookStore) Pending() ([]WebhookDelivery, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	files, err := ioutil.ReadDir(f.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to ReadDir")
+	}
+
+	deliveries := []WebhookDelivery{}
+
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		deliveryJSON, err := ioutil.ReadFile(filepath.Join(f.dir, file.Name()))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to ReadFile")
+		}
+
+		delivery := WebhookDelivery{}
+		if err := json.Unmarshal(deliveryJSON, &delivery); err != nil {
+			return nil, errors.Wrapf(err, "failed to Unmarshal %s", file.Name())
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func (f *FileWebhookStore) path(id string) string {
+	return filepath.Join(f.dir, id+".json")
+}
@@ -0,0 +1,169 @@
+package rfaas
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records the webhooks it receives, failing the first `failures` requests
+type webhookReceiver struct {
+	secret   []byte
+	failures int
+	attempts int
+	payloads []*WebhookPayload
+	received chan struct{}
+	lock     sync.Mutex
+}
+
+func (w *webhookReceiver) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.attempts++
+
+	if w.attempts <= w.failures {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	payload := &WebhookPayload{}
+
+	if w.secret != nil {
+		verified, err := VerifyWebhook(r, w.secret)
+		if err != nil {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		payload = verified
+	} else if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.payloads = append(w.payloads, payload)
+	w.received <- struct{}{}
+}
+
+func (w *webhookReceiver) wait(t *testing.T) {
+	select {
+	case <-w.received:
+	case <-time.After(time.Second * 5):
+		t.Fatal("webhook was not received")
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	secret := []byte("webhook-secret")
+
+	receiver := &webhookReceiver{secret: secret, failures: 2, received: make(chan struct{}, 10)}
+	callbackServer := httptest.NewServer(receiver)
+	defer callbackServer.Close()
+
+	baseURL := startTestServer(t, func(s *Server) {
+		s.Configure(UseWebhookSecret(secret), UseWebhookRetry(3, time.Millisecond*10))
+	})
+
+	status, body := doRequest(t, http.MethodPost, baseURL+"/do/echo?callback="+callbackServer.URL, []byte("hello"))
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", status, string(body))
+	}
+
+	resp := doResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	receiver.wait(t)
+
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	if receiver.attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", receiver.attempts)
+	}
+
+	payload := receiver.payloads[0]
+
+	if payload.ID != resp.ResultID || payload.Status != ResultStatusComplete || string(payload.Result) != "hello" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+}
+
+func TestWebhookFailedJob(t *testing.T) {
+	receiver := &webhookReceiver{received: make(chan struct{}, 10)}
+	callbackServer := httptest.NewServer(receiver)
+	defer callbackServer.Close()
+
+	baseURL := startTestServer(t, nil)
+
+	doRequest(t, http.MethodPost, baseURL+"/do/echo?callback="+callbackServer.URL, []byte("error"))
+
+	receiver.wait(t)
+
+	receiver.lock.Lock()
+	defer receiver.lock.Unlock()
+
+	if payload := receiver.payloads[0]; payload.Status != ResultStatusFailed || payload.Error == "" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+}
+
+func TestWebhookResume(t *testing.T) {
+	receiver := &webhookReceiver{received: make(chan struct{}, 10)}
+	callbackServer := httptest.NewServer(receiver)
+	defer callbackServer.Close()
+
+	dir, err := ioutil.TempDir("", "rfaas-webhooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileWebhookStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a delivery left behind by an earlier server that stopped before sending it
+	body, _ := json.Marshal(WebhookPayload{ID: "abc", Status: ResultStatusComplete})
+
+	if err := store.Save(WebhookDelivery{ID: "pending", URL: callbackServer.URL, Body: body, Attempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	startTestServer(t, func(s *Server) {
+		s.Configure(UseWebhookStore(store), UseWebhookRetry(3, time.Millisecond*10))
+	})
+
+	receiver.wait(t)
+
+	receiver.lock.Lock()
+	if receiver.payloads[0].ID != "abc" {
+		t.Errorf("expected resumed payload, got %+v", receiver.payloads[0])
+	}
+	receiver.lock.Unlock()
+
+	// once delivered, it should be removed from the store
+	for i := 0; i < 50; i++ {
+		pending, err := store.Pending()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(pending) == 0 {
+			return
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	t.Error("delivery was not removed from the store")
+}
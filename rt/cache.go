@@ -1,6 +1,10 @@
 package rt
 
 import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,6 +14,12 @@ import (
 // ErrCacheKeyNotFound is returned when a non-existent cache key is requested
 var ErrCacheKeyNotFound = errors.New("key not found")
 
+// ErrCacheValueNotInt is returned when Increment is called on a value that is not an integer
+var ErrCacheValueNotInt = errors.New("value is not an integer")
+
+// ErrCacheOpNotSupported is returned when a Cache does not implement an optional extension interface
+var ErrCacheOpNotSupported = errors.New("cache operation not supported")
+
 // Cache represents access to a persistent cache
 type Cache interface {
 	Set(key string, val []byte, ttl int) error
@@ -17,6 +27,37 @@ type Cache interface {
 	Delete(key string) error
 }
 
+// AtomicCache is an optional extension of Cache that allows values to be modified atomically,
+// enabling things like counters and locks to be built safely
+type AtomicCache interface {
+	Cache
+
+	// Increment adds delta to the integer stored at key (treating a missing key as 0) and returns the new value
+	Increment(key string, delta int64) (int64, error)
+	// CompareAndSwap sets key to new only if its current value is equal to old, and reports whether it did so
+	CompareAndSwap(key string, old, new []byte, ttl int) (bool, error)
+	// SetIfAbsent sets key to val only if key does not exist, and reports whether it did so
+	SetIfAbsent(key string, val []byte, ttl int) (bool, error)
+}
+
+// ExpiringCache is an optional extension of Cache that allows the TTL of existing keys to be managed
+type ExpiringCache interface {
+	Cache
+
+	// Expire sets the TTL (in seconds) of an existing key, a ttl of 0 removes any expiry
+	Expire(key string, ttl int) error
+	// TTL returns the number of seconds until key expires, or 0 if it does not expire
+	TTL(key string) (int, error)
+}
+
+// EnumerableCache is an optional extension of Cache that allows keys to be listed
+type EnumerableCache interface {
+	Cache
+
+	// Keys returns the (sorted) keys that begin with prefix, pass an empty prefix to list all keys
+	Keys(prefix string) ([]string, error)
+}
+
 // memoryCache is a "default" cache implementation for Reactr
 type memoryCache struct {
 	values map[string]*uniqueVal
@@ -26,7 +67,13 @@ type memoryCache struct {
 
 // this is used to 1) allow pointers and 2) ensure checks for unique values are cheaper (pointer equality)
 type uniqueVal struct {
-	val []byte
+	val     []byte
+	expires time.Time
+}
+
+// NewMemoryCache creates the in-memory Cache that Reactr uses by default, which is useful for testing Runnables outside of a Reactr
+func NewMemoryCache() Cache {
+	return newMemoryCache()
 }
 
 func newMemoryCache() *memoryCache {
@@ -42,25 +89,7 @@ func (m *memoryCache) Set(key string, val []byte, ttl int) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	uVal := &uniqueVal{
-		val: val,
-	}
-
-	m.values[key] = uVal
-
-	if ttl > 0 {
-		go func() {
-			<-time.After(time.Second * time.Duration(ttl))
-
-			m.lock.Lock()
-			defer m.lock.Unlock()
-
-			currentVal := m.values[key]
-			if currentVal == uVal {
-				delete(m.values, key)
-			}
-		}()
-	}
+	m.set(key, val, ttl)
 
 	return nil
 }
@@ -69,8 +98,8 @@ func (m *memoryCache) Get(key string) ([]byte, error) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	uVal, exists := m.values[key]
-	if !exists {
+	uVal := m.get(key)
+	if uVal == nil {
 		return nil, ErrCacheKeyNotFound
 	}
 
@@ -90,3 +119,160 @@ func (m *memoryCache) Delete(key string) error {
 
 	return nil
 }
+
+func (m *memoryCache) Increment(key string, delta int64) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var current int64
+	var expires time.Time
+
+	if uVal := m.get(key); uVal != nil {
+		parsed, err := strconv.ParseInt(string(uVal.val), 10, 64)
+		if err != nil {
+			return 0, ErrCacheValueNotInt
+		}
+
+		current = parsed
+		expires = uVal.expires
+	}
+
+	next := current + delta
+
+	// incrementing should not affect the key's expiry, so the existing deadline is kept
+	m.setWithExpiry(key, []byte(strconv.FormatInt(next, 10)), expires)
+
+	return next, nil
+}
+
+func (m *memoryCache) CompareAndSwap(key string, old, new []byte, ttl int) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	uVal := m.get(key)
+	if uVal == nil {
+		return false, ErrCacheKeyNotFound
+	}
+
+	if !bytes.Equal(uVal.val, old) {
+		return false, nil
+	}
+
+	m.set(key, new, ttl)
+
+	return true, nil
+}
+
+func (m *memoryCache) SetIfAbsent(key string, val []byte, ttl int) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if uVal := m.get(key); uVal != nil {
+		return false, nil
+	}
+
+	m.set(key, val, ttl)
+
+	return true, nil
+}
+
+func (m *memoryCache) Expire(key string, ttl int) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	uVal := m.get(key)
+	if uVal == nil {
+		return ErrCacheKeyNotFound
+	}
+
+	m.set(key, uVal.val, ttl)
+
+	return nil
+}
+
+func (m *memoryCache) TTL(key string) (int, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	uVal := m.get(key)
+	if uVal == nil {
+		return 0, ErrCacheKeyNotFound
+	}
+
+	if uVal.expires.IsZero() {
+		return 0, nil
+	}
+
+	// round up so that a key which has not yet expired never reports a TTL of 0
+	return int((time.Until(uVal.expires) + time.Second - 1) / time.Second), nil
+}
+
+func (m *memoryCache) Keys(prefix string) ([]string, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	keys := []string{}
+
+	for key := range m.values {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		if uVal := m.get(key); uVal != nil {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// get returns the value for key if it exists and has not expired. THIS DOES NOT LOCK. THE CALLER MUST LOCK.
+func (m *memoryCache) get(key string) *uniqueVal {
+	uVal, exists := m.values[key]
+	if !exists {
+		return nil
+	}
+
+	// the expiry goroutine may not have run yet, so double check
+	if !uVal.expires.IsZero() && !time.Now().Before(uVal.expires) {
+		return nil
+	}
+
+	return uVal
+}
+
+// set sets the value for key with a ttl in seconds. THIS DOES NOT LOCK. THE CALLER MUST LOCK.
+func (m *memoryCache) set(key string, val []byte, ttl int) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(time.Second * time.Duration(ttl))
+	}
+
+	m.setWithExpiry(key, val, expires)
+}
+
+// setWithExpiry sets the value for key with an absolute expiry. THIS DOES NOT LOCK. THE CALLER MUST LOCK.
+func (m *memoryCache) setWithExpiry(key string, val []byte, expires time.Time) {
+	uVal := &uniqueVal{
+		val:     val,
+		expires: expires,
+	}
+
+	m.values[key] = uVal
+
+	if !expires.IsZero() {
+		go func() {
+			<-time.After(time.Until(expires))
+
+			m.lock.Lock()
+			defer m.lock.Unlock()
+
+			currentVal := m.values[key]
+			if currentVal == uVal {
+				delete(m.values, key)
+			}
+		}()
+	}
+}
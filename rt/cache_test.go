@@ -44,8 +44,8 @@ func (c *getTester) OnChange(_ ChangeEvent) error {
 
 func TestCacheGetSet(t *testing.T) {
 	h := New()
-	h.Handle("set", &setTester{})
-	h.Handle("get", &getTester{})
+	h.Handle("set", &setTester{}, SharedCache())
+	h.Handle("get", &getTester{}, SharedCache())
 
 	_, err := h.Do(NewJob("set", "very important information")).Then()
 	if err != nil {
@@ -66,8 +66,8 @@ func TestCacheGetSet(t *testing.T) {
 
 func TestCacheGetSetWithTTL(t *testing.T) {
 	h := New()
-	h.Handle("set", &setTester{})
-	h.Handle("get", &getTester{})
+	h.Handle("set", &setTester{}, SharedCache())
+	h.Handle("get", &getTester{}, SharedCache())
 
 	_, err := h.Do(NewJob("set", "very important information")).Then()
 	if err != nil {
@@ -83,3 +83,109 @@ func TestCacheGetSetWithTTL(t *testing.T) {
 		return
 	}
 }
+
+type incrTester struct{}
+
+func (c *incrTester) Run(job Job, ctx *Ctx) (interface{}, error) {
+	atomic, ok := ctx.Cache.(AtomicCache)
+	if !ok {
+		return nil, ErrCacheOpNotSupported
+	}
+
+	val, err := atomic.Increment(job.String(), 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return int(val), nil
+}
+
+// OnChange runs on worker changes
+func (c *incrTester) OnChange(_ ChangeEvent) error {
+	return nil
+}
+
+func TestCacheIncrement(t *testing.T) {
+	h := New()
+	doIncr := h.Handle("incr", &incrTester{}, PoolSize(5))
+
+	grp := NewGroup()
+	for i := 0; i < 100; i++ {
+		grp.Add(doIncr("counter"))
+	}
+
+	if err := grp.Wait(); err != nil {
+		t.Error(errors.Wrap(err, "failed to grp.Wait"))
+		return
+	}
+
+	val, err := doIncr("counter").ThenInt()
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to incr"))
+		return
+	}
+
+	if val != 101 {
+		t.Error("expected counter to be 101, got", val)
+	}
+}
+
+func TestCacheAtomicOps(t *testing.T) {
+	m := newMemoryCache()
+
+	if set, _ := m.SetIfAbsent("lock", []byte("a"), 0); !set {
+		t.Error("expected SetIfAbsent to set missing key")
+	}
+
+	if set, _ := m.SetIfAbsent("lock", []byte("b"), 0); set {
+		t.Error("expected SetIfAbsent not to set existing key")
+	}
+
+	if swapped, _ := m.CompareAndSwap("lock", []byte("b"), []byte("c"), 0); swapped {
+		t.Error("expected CompareAndSwap with wrong old value to fail")
+	}
+
+	if swapped, _ := m.CompareAndSwap("lock", []byte("a"), []byte("c"), 0); !swapped {
+		t.Error("expected CompareAndSwap with correct old value to succeed")
+	}
+
+	if _, err := m.Increment("lock", 1); err != ErrCacheValueNotInt {
+		t.Error("expected ErrCacheValueNotInt, got", err)
+	}
+}
+
+func TestCacheExpireAndKeys(t *testing.T) {
+	m := newMemoryCache()
+
+	m.Set("user:1", []byte("one"), 0)
+	m.Set("user:2", []byte("two"), 0)
+	m.Set("session:1", []byte("s"), 0)
+
+	keys, _ := m.Keys("user:")
+	if len(keys) != 2 || keys[0] != "user:1" || keys[1] != "user:2" {
+		t.Error("unexpected keys:", keys)
+	}
+
+	if ttl, _ := m.TTL("user:1"); ttl != 0 {
+		t.Error("expected TTL of 0, got", ttl)
+	}
+
+	if err := m.Expire("user:1", 1); err != nil {
+		t.Error(errors.Wrap(err, "failed to Expire"))
+		return
+	}
+
+	if ttl, _ := m.TTL("user:1"); ttl != 1 {
+		t.Error("expected TTL of 1, got", ttl)
+	}
+
+	<-time.After(time.Millisecond * 1100)
+
+	if _, err := m.Get("user:1"); err != ErrCacheKeyNotFound {
+		t.Error("expected key to have expired")
+	}
+
+	if err := m.Expire("missing", 1); err != ErrCacheKeyNotFound {
+		t.Error("expected ErrCacheKeyNotFound, got", err)
+	}
+}
@@ -0,0 +1,55 @@
+package rt
+
+import "time"
+
+// Clock provides the current time and timers to Reactr, allowing time to be controlled in tests (see the rttest package)
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the default Clock, backed by the time package
+type systemClock struct{}
+
+func (s systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (s systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// ReactrOption is a function that modifies reactrOpts
+type ReactrOption func(reactrOpts) reactrOpts
+
+type reactrOpts struct {
+	clock       Clock
+	synchronous bool
+}
+
+func defaultReactrOpts() reactrOpts {
+	o := reactrOpts{
+		clock:       systemClock{},
+		synchronous: false,
+	}
+
+	return o
+}
+
+// UseClock returns a ReactrOption that sets the Clock used for schedules and job timeouts
+func UseClock(clock Clock) ReactrOption {
+	return func(opts reactrOpts) reactrOpts {
+		opts.clock = clock
+		return opts
+	}
+}
+
+// Synchronous returns a ReactrOption that causes jobs to be run on the goroutine that schedules them
+// rather than on a worker's goroutines, such that the Result returned from Do is complete when Do returns.
+// This is intended for testing, and should not be used with Runnables that are not safe to call concurrently.
+func Synchronous() ReactrOption {
+	return func(opts reactrOpts) reactrOpts {
+		opts.synchronous = true
+		return opts
+	}
+}
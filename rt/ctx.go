@@ -1,6 +1,10 @@
 package rt
 
-import "github.com/pkg/errors"
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 var errDoFuncNotSet = errors.New("do func has not been set")
 
@@ -8,18 +12,46 @@ var errDoFuncNotSet = errors.New("do func has not been set")
 type Ctx struct {
 	Cache  Cache
 	doFunc DoFunc
+	meta   map[string]string
+
+	// the time by which the job being run must complete, taking into account its deadline and timeout
+	deadline time.Time
 }
 
-func newCtx(cache Cache, doFunc DoFunc) *Ctx {
+func newCtx(cache Cache, doFunc DoFunc, meta map[string]string, deadline time.Time) *Ctx {
 	c := &Ctx{
-		Cache:  cache,
-		doFunc: doFunc,
+		Cache:    cache,
+		doFunc:   doFunc,
+		meta:     meta,
+		deadline: deadline,
 	}
 
 	return c
 }
 
-// Do runs a new job
+// NewCtx creates a job context outside of a Reactr, which is useful for calling a Runnable's Run function directly.
+// If cache is nil, an in-memory cache is used. Jobs passed to the context's Do function are passed to doFunc.
+func NewCtx(cache Cache, doFunc DoFunc) *Ctx {
+	if cache == nil {
+		cache = newMemoryCache()
+	}
+
+	return newCtx(cache, doFunc, nil, time.Time{})
+}
+
+// Meta returns the value of a metadata key from the job being run, or an empty string if it is not set
+func (c *Ctx) Meta(key string) string {
+	return c.meta[key]
+}
+
+// Deadline returns the time by which the job being run must complete (based on its deadline and its handler's timeout),
+// and false if there is no such time
+func (c *Ctx) Deadline() (time.Time, bool) {
+	return c.deadline, !c.deadline.IsZero()
+}
+
+// Do runs a new job, which inherits any metadata from the job being run that it does not set itself.
+// The new job also inherits the Ctx's deadline, unless it has an earlier deadline of its own.
 func (c *Ctx) Do(job Job) *Result {
 	if c.doFunc == nil {
 		r := newResult(job.uuid, func(_ string) {})
@@ -27,5 +59,15 @@ func (c *Ctx) Do(job Job) *Result {
 		return r
 	}
 
+	for k, v := range c.meta {
+		if _, exists := job.meta[k]; !exists {
+			job = job.WithMeta(k, v)
+		}
+	}
+
+	if !c.deadline.IsZero() && (job.deadline.IsZero() || c.deadline.Before(job.deadline)) {
+		job.deadline = c.deadline
+	}
+
 	return c.doFunc(job)
 }
@@ -52,3 +52,28 @@ func (g *Group) Wait() error {
 
 	return wg.Wait()
 }
+
+// Results waits for all results to come in and returns each of their values and errors, in the order they were added.
+// Since a Result can only be consumed once, a Group should be waited on using either Wait or Results, not both.
+func (g *Group) Results() ([]interface{}, []error) {
+	g.Lock()
+	defer g.Unlock()
+
+	data := make([]interface{}, len(g.results))
+	errs := make([]error, len(g.results))
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(g.results))
+
+	for i := range g.results {
+		go func(i int) {
+			defer wg.Done()
+
+			data[i], errs[i] = g.results[i].Then()
+		}(i)
+	}
+
+	wg.Wait()
+
+	return data, errs
+}
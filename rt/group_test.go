@@ -80,3 +80,32 @@ func TestReactrChainedGroup(t *testing.T) {
 		t.Error(errors.Wrap(err, "failed to doGrp"))
 	}
 }
+
+func TestGroupResults(t *testing.T) {
+	h := New()
+
+	doGeneric := h.Handle("generic", generic{})
+
+	grp := NewGroup()
+	grp.Add(doGeneric("last"))
+	grp.Add(doGeneric("fail"))
+	grp.Add(doGeneric("first"))
+
+	data, errs := grp.Results()
+
+	if len(data) != 3 || len(errs) != 3 {
+		t.Fatalf("expected 3 results, got %d and %d errors", len(data), len(errs))
+	}
+
+	if data[0] != "last" || errs[0] != nil {
+		t.Errorf("unexpected first result %v, %v", data[0], errs[0])
+	}
+
+	if errs[1] == nil {
+		t.Error("expected error for second result")
+	}
+
+	if errs[2] != nil {
+		t.Errorf("unexpected error for third result: %s", errs[2])
+	}
+}
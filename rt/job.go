@@ -3,21 +3,25 @@ package rt
 import (
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 // JobReference is a lightweight reference to a Job
 type JobReference struct {
-	uuid    string
-	jobType string
-	result  *Result
+	uuid         string
+	jobType      string
+	partitionKey string
+	result       *Result
 }
 
 // Job describes a job to be done
 type Job struct {
 	JobReference
 	data       interface{}
+	meta       map[string]string
+	deadline   time.Time
 	resultData interface{}
 	resultErr  error
 }
@@ -40,6 +44,11 @@ func (j JobReference) UUID() string {
 	return j.uuid
 }
 
+// Type returns the Job's type
+func (j JobReference) Type() string {
+	return j.jobType
+}
+
 // Reference returns a reference to the Job
 func (j Job) Reference() JobReference {
 	return j.JobReference
@@ -90,6 +99,66 @@ func (j Job) Data() interface{} {
 	return j.data
 }
 
+// WithData returns a copy of the Job with its data replaced, allowing Middleware to modify a job before it is run
+func (j Job) WithData(data interface{}) Job {
+	j.data = data
+
+	return j
+}
+
+// WithPartitionKey returns a copy of the job with a partition key set. If the job's handler uses the
+// PartitionByKey option, all jobs with the same partition key are run in order by the same workThread.
+func (j Job) WithPartitionKey(key string) Job {
+	j.partitionKey = key
+
+	return j
+}
+
+// PartitionKey returns the job's partition key, or an empty string if it is not set
+func (j JobReference) PartitionKey() string {
+	return j.partitionKey
+}
+
+// WithMeta returns a copy of the Job with the metadata key set to val.
+// Metadata is propagated to any child jobs scheduled with Ctx.Do while the job is running.
+func (j Job) WithMeta(key, val string) Job {
+	meta := j.Metadata()
+	meta[key] = val
+
+	j.meta = meta
+
+	return j
+}
+
+// Meta returns the value of a metadata key, or an empty string if it is not set
+func (j Job) Meta(key string) string {
+	return j.meta[key]
+}
+
+// Metadata returns a copy of all of the job's metadata
+func (j Job) Metadata() map[string]string {
+	meta := make(map[string]string, len(j.meta))
+	for k, v := range j.meta {
+		meta[k] = v
+	}
+
+	return meta
+}
+
+// WithDeadline returns a copy of the Job with an absolute deadline. If the deadline has passed before the job runs,
+// it fails with ErrJobDeadlineExceeded without being run, and if it passes while the job is running, the job's result
+// is ErrJobDeadlineExceeded. Child jobs scheduled with Ctx.Do inherit the deadline unless they set an earlier one.
+func (j Job) WithDeadline(deadline time.Time) Job {
+	j.deadline = deadline
+
+	return j
+}
+
+// Deadline returns the Job's deadline, and false if it does not have one
+func (j Job) Deadline() (time.Time, bool) {
+	return j.deadline, !j.deadline.IsZero()
+}
+
 // loadResult has a pointer reciever such that it actually modifies the object it's being called on
 func (j *Job) loadResult(resultData interface{}, errString string) {
 	j.resultData = resultData
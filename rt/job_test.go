@@ -1,6 +1,9 @@
 package rt
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestCreateJob(t *testing.T) {
 	job := NewJob("test", []byte("{\"some\": 1}"))
@@ -17,3 +20,113 @@ func TestCreateJob(t *testing.T) {
 		t.Error("job's result should be empty, is not")
 	}
 }
+
+type metaRunner struct{}
+
+func (m metaRunner) Run(job Job, ctx *Ctx) (interface{}, error) {
+	if job.String() == "parent" {
+		return ctx.Do(NewJob("meta", "child").WithMeta("child", "set")), nil
+	}
+
+	return ctx.Meta("tenant") + ":" + job.Meta("tenant") + ":" + job.Meta("child"), nil
+}
+
+func (m metaRunner) OnChange(change ChangeEvent) error { return nil }
+
+func TestJobMetaPropagation(t *testing.T) {
+	h := New()
+	h.Handle("meta", metaRunner{})
+
+	job := NewJob("meta", "parent").WithMeta("tenant", "abc")
+
+	res, err := h.Do(job).Then()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res.(string) != "abc:abc:set" {
+		t.Error("expected 'abc:abc:set', got", res)
+	}
+
+	// WithMeta must not modify the original job
+	if copied := job.WithMeta("tenant", "def"); job.Meta("tenant") != "abc" || copied.Meta("tenant") != "def" {
+		t.Error("WithMeta modified the original job")
+	}
+}
+
+type deadlineRunner struct {
+	ran chan bool
+}
+
+func (d deadlineRunner) Run(job Job, ctx *Ctx) (interface{}, error) {
+	d.ran <- true
+
+	switch job.String() {
+	case "parent":
+		return ctx.Do(NewJob("deadline", "child")), nil
+	case "slow":
+		time.Sleep(time.Millisecond * 200)
+		return nil, nil
+	}
+
+	deadline, hasDeadline := ctx.Deadline()
+	if !hasDeadline {
+		return nil, nil
+	}
+
+	return deadline, nil
+}
+
+func (d deadlineRunner) OnChange(change ChangeEvent) error { return nil }
+
+func TestJobDeadline(t *testing.T) {
+	h := New()
+
+	runner := deadlineRunner{ran: make(chan bool, 10)}
+	h.Handle("deadline", runner, TimeoutSeconds(10))
+	h.Handle("noTimeout", runner)
+
+	// a job whose deadline has passed should not be run
+	if _, err := h.Do(NewJob("deadline", "late").WithDeadline(time.Now().Add(-time.Second))).Then(); err != ErrJobDeadlineExceeded {
+		t.Errorf("expected ErrJobDeadlineExceeded, got %v", err)
+	}
+
+	select {
+	case <-runner.ran:
+		t.Error("job with passed deadline was run")
+	default:
+	}
+
+	// a job that runs past its deadline should fail
+	if _, err := h.Do(NewJob("deadline", "slow").WithDeadline(time.Now().Add(time.Millisecond * 50))).Then(); err != ErrJobDeadlineExceeded {
+		t.Errorf("expected ErrJobDeadlineExceeded, got %v", err)
+	}
+
+	// a child job should inherit the parent's deadline
+	deadline := time.Now().Add(time.Second * 2)
+
+	res, err := h.Do(NewJob("deadline", "parent").WithDeadline(deadline)).Then()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !res.(time.Time).Equal(deadline) {
+		t.Errorf("expected child deadline to be %s, got %s", deadline, res.(time.Time))
+	}
+
+	// without a deadline, the child inherits the parent's timeout
+	res, err = h.Do(NewJob("deadline", "parent")).Then()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if until := time.Until(res.(time.Time)); until <= 0 || until > time.Second*10 {
+		t.Errorf("expected child deadline to be within parent's timeout, got %s", until)
+	}
+
+	// with neither a deadline nor a timeout, there is no deadline
+	if res, err := h.Do(NewJob("noTimeout", "child")).Then(); err != nil || res != nil {
+		t.Errorf("expected no deadline, got %v, %v", res, err)
+	}
+}
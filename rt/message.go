@@ -0,0 +1,238 @@
+package rt
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/grav/grav"
+)
+
+// ErrorCodeJobFailed and others are the codes included in a JobError
+const (
+	ErrorCodeJobFailed    = 1
+	ErrorCodeJobTimeout   = 2
+	ErrorCodeEncodeFailed = 3
+)
+
+// JobError is a structured error sent as the payload of a reactr.joberr message
+type JobError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	JobUUID string `json:"jobUUID"`
+}
+
+// Error returns the error's message
+func (j *JobError) Error() string {
+	return j.Message
+}
+
+// coder can be implemented by errors returned from a Runnable to control the code sent in a JobError
+type coder interface {
+	Code() int
+}
+
+// newJobError creates a JobError for an error returned by a job
+func newJobError(jobUUID string, err error) *JobError {
+	code := ErrorCodeJobFailed
+	if c, ok := err.(coder); ok {
+		code = c.Code()
+	} else if err == ErrJobTimeout || err == ErrJobDeadlineExceeded {
+		code = ErrorCodeJobTimeout
+	}
+
+	j := &JobError{
+		Code:    code,
+		Message: err.Error(),
+		JobUUID: jobUUID,
+	}
+
+	return j
+}
+
+// jobErrorFromMsg extracts a JobError from a reactr.joberr message,
+// falling back to the raw message data for senders that do not use JobError
+func jobErrorFromMsg(msg grav.Message) *JobError {
+	jobErr := &JobError{}
+	if err := msg.UnmarshalData(jobErr); err != nil || jobErr.Message == "" {
+		return &JobError{Code: ErrorCodeJobFailed, Message: string(msg.Data())}
+	}
+
+	return jobErr
+}
+
+// MetadataMessage is a grav.Message that carries metadata, such as MetaMsg. When Listen receives a MetadataMessage, its metadata
+// is copied to the triggered job, and the job's metadata is copied to the reply (which is wrapped in a MetaMsg if needed).
+type MetadataMessage interface {
+	grav.Message
+	Metadata() map[string]string
+	SetMetadata(key, val string)
+}
+
+// MetaMsg is a MetadataMessage that wraps another grav.Message. It is marshalled in grav's default message format,
+// so that it can be decoded by grav's transports, with the metadata alongside the data in the payload. Messages decoded
+// with grav.MsgFromBytes (as transports do) keep their type, UUID and data but lose the metadata, which can be restored
+// by decoding the same bytes with MetaMsgFromBytes.
+type MetaMsg struct {
+	grav.Message
+	meta map[string]string
+	lock sync.RWMutex
+}
+
+// metaMsgJSON is grav's default message format, with the metadata added to the payload
+type metaMsgJSON struct {
+	Meta struct {
+		UUID      string    `json:"uuid"`
+		ParentID  string    `json:"parent_id"`
+		ReplyTo   string    `json:"response_to"`
+		MsgType   string    `json:"msg_type"`
+		Timestamp time.Time `json:"timestamp"`
+	} `json:"meta"`
+	Payload struct {
+		Data     []byte            `json:"data"`
+		Metadata map[string]string `json:"metadata,omitempty"`
+	} `json:"payload"`
+}
+
+// NewMetaMsg creates a MetaMsg containing a new grav message with the given metadata
+func NewMetaMsg(msgType string, data []byte, meta map[string]string) *MetaMsg {
+	return WithMetadata(grav.NewMsg(msgType, data), meta)
+}
+
+// WithMetadata wraps msg in a MetaMsg with a copy of the given metadata
+func WithMetadata(msg grav.Message, meta map[string]string) *MetaMsg {
+	m := &MetaMsg{
+		Message: msg,
+		meta:    map[string]string{},
+	}
+
+	for k, v := range meta {
+		m.meta[k] = v
+	}
+
+	return m
+}
+
+// MetaMsgFromBytes returns a MetaMsg that has been unmarshalled from bytes in grav's default message format
+func MetaMsgFromBytes(bytes []byte) (*MetaMsg, error) {
+	m := &MetaMsg{}
+	if err := m.Unmarshal(bytes); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Metadata returns a copy of the message's metadata
+func (m *MetaMsg) Metadata() map[string]string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	meta := make(map[string]string, len(m.meta))
+	for k, v := range m.meta {
+		meta[k] = v
+	}
+
+	return meta
+}
+
+// SetMetadata sets a metadata key
+func (m *MetaMsg) SetMetadata(key, val string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.meta[key] = val
+}
+
+// Marshal encodes the message in grav's default format, with the metadata in its payload
+func (m *MetaMsg) Marshal() ([]byte, error) {
+	msgJSON := metaMsgJSON{}
+	msgJSON.Meta.UUID = m.UUID()
+	msgJSON.Meta.ParentID = m.ParentID()
+	msgJSON.Meta.ReplyTo = m.ReplyTo()
+	msgJSON.Meta.MsgType = m.Type()
+	msgJSON.Meta.Timestamp = m.Timestamp()
+	msgJSON.Payload.Data = m.Data()
+	msgJSON.Payload.Metadata = m.Metadata()
+
+	return json.Marshal(msgJSON)
+}
+
+// Unmarshal decodes a MetaMsg encoded by Marshal, or any message in grav's default format
+func (m *MetaMsg) Unmarshal(bytes []byte) error {
+	msgJSON := metaMsgJSON{}
+	if err := json.Unmarshal(bytes, &msgJSON); err != nil {
+		return errors.Wrap(err, "failed to Unmarshal message")
+	}
+
+	msg, err := grav.MsgFromBytes(bytes)
+	if err != nil {
+		return errors.Wrap(err, "failed to MsgFromBytes")
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.Message = msg
+	m.meta = map[string]string{}
+
+	for k, v := range msgJSON.Payload.Metadata {
+		m.meta[k] = v
+	}
+
+	return nil
+}
+
+// MessageEncoder converts the (non-nil) result of a job triggered by a message into a reply message.
+// msgType is the message type that the reply should use.
+type MessageEncoder func(msgType string, result interface{}) (grav.Message, error)
+
+// DefaultMessageEncoder sends []byte results as-is, string results as bytes, and JSON-marshals anything else
+func DefaultMessageEncoder(msgType string, result interface{}) (grav.Message, error) {
+	if bytes, isBytes := result.([]byte); isBytes {
+		return grav.NewMsg(msgType, bytes), nil
+	} else if resultString, isString := result.(string); isString {
+		return grav.NewMsg(msgType, []byte(resultString)), nil
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to Marshal job result")
+	}
+
+	return grav.NewMsg(msgType, resultJSON), nil
+}
+
+// ListenOption is a function that modifies listenOpts
+type ListenOption func(listenOpts) listenOpts
+
+type listenOpts struct {
+	encoder       MessageEncoder
+	resultMsgType string
+}
+
+func defaultListenOpts() listenOpts {
+	o := listenOpts{
+		encoder:       DefaultMessageEncoder,
+		resultMsgType: MsgTypeReactrResult,
+	}
+
+	return o
+}
+
+// UseEncoder returns a ListenOption that sets the MessageEncoder used to convert job results into reply messages
+func UseEncoder(encoder MessageEncoder) ListenOption {
+	return func(opts listenOpts) listenOpts {
+		opts.encoder = encoder
+		return opts
+	}
+}
+
+// ResultMsgType returns a ListenOption that sets the message type used for job results instead of reactr.result
+func ResultMsgType(msgType string) ListenOption {
+	return func(opts listenOpts) listenOpts {
+		opts.resultMsgType = msgType
+		return opts
+	}
+}
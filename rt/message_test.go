@@ -3,6 +3,7 @@ package rt
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/suborbital/grav/grav"
@@ -103,3 +104,150 @@ func TestHandleMessageNilResult(t *testing.T) {
 		t.Error(errors.Wrap(err, "failed to counter.Wait"))
 	}
 }
+
+// to test results that cannot be encoded
+type chanRunner struct{}
+
+func (c *chanRunner) Run(job Job, ctx *Ctx) (interface{}, error) {
+	return make(chan bool), nil
+}
+
+func (c *chanRunner) OnChange(change ChangeEvent) error { return nil }
+
+func TestListenEncodeFailure(t *testing.T) {
+	r := New()
+	g := grav.New()
+
+	r.HandleMsg(g.Connect(), "reactr.testchan", &chanRunner{})
+
+	pod := g.Connect()
+
+	_, err := r.RemoteDo(pod, "reactr.testchan", "hi").Then()
+
+	jobErr, isJobErr := err.(*JobError)
+	if !isJobErr {
+		t.Error("expected *JobError, got", err)
+		return
+	}
+
+	if jobErr.Code != ErrorCodeEncodeFailed || jobErr.JobUUID == "" {
+		t.Error("unexpected JobError", jobErr)
+	}
+}
+
+func TestListenWithOptions(t *testing.T) {
+	r := New()
+	g := grav.New()
+
+	r.Handle("reactr.testopts", generic{})
+	r.Listen(g.Connect(), "reactr.testopts", ResultMsgType("reactr.testresult"), UseEncoder(func(msgType string, result interface{}) (grav.Message, error) {
+		return grav.NewMsg(msgType, []byte(fmt.Sprintf("encoded %s", result))), nil
+	}))
+
+	res, err := r.RemoteDo(g.Connect(), "reactr.testopts", "hi").Then()
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to RemoteDo"))
+		return
+	}
+
+	msg, isMsg := res.(grav.Message)
+	if !isMsg {
+		t.Error("expected grav.Message result, got", res)
+		return
+	}
+
+	if msg.Type() != "reactr.testresult" || string(msg.Data()) != "encoded hi" {
+		t.Error("unexpected result message", msg.Type(), string(msg.Data()))
+	}
+}
+
+func TestListenJobError(t *testing.T) {
+	r := New()
+	g := grav.New()
+
+	r.HandleMsg(g.Connect(), "reactr.testtimeout", timeoutRunner{}, TimeoutSeconds(1))
+
+	_, err := r.RemoteDo(g.Connect(), "reactr.testtimeout", "hi").Then()
+
+	jobErr, isJobErr := err.(*JobError)
+	if !isJobErr {
+		t.Error("expected *JobError, got", err)
+		return
+	}
+
+	if jobErr.Code != ErrorCodeJobTimeout || jobErr.Message != ErrJobTimeout.Error() {
+		t.Error("unexpected JobError", jobErr)
+	}
+}
+
+func TestMetaMsgRoundTrip(t *testing.T) {
+	msg := NewMetaMsg(msgTypeTester, []byte("hello"), map[string]string{"tenant": "abc"})
+	msg.SetMetadata("client", "xyz")
+
+	msgBytes, err := msg.Marshal()
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to Marshal"))
+		return
+	}
+
+	// grav's transports decode messages with MsgFromBytes, which must see the same message
+	gravMsg, err := grav.MsgFromBytes(msgBytes)
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to MsgFromBytes"))
+		return
+	}
+
+	decoded, err := MetaMsgFromBytes(msgBytes)
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to MetaMsgFromBytes"))
+		return
+	}
+
+	for _, m := range []grav.Message{gravMsg, decoded} {
+		if m.UUID() != msg.UUID() || m.Type() != msgTypeTester || string(m.Data()) != "hello" || !m.Timestamp().Equal(msg.Timestamp()) {
+			t.Error("unexpected message", m.UUID(), m.Type(), string(m.Data()))
+		}
+	}
+
+	if meta := decoded.Metadata(); len(meta) != 2 || meta["tenant"] != "abc" || meta["client"] != "xyz" {
+		t.Error("unexpected metadata", meta)
+	}
+
+	// messages without metadata can be decoded too
+	plainBytes, _ := grav.NewMsg(msgTypeTester, []byte("plain")).Marshal()
+
+	if plain, err := MetaMsgFromBytes(plainBytes); err != nil || string(plain.Data()) != "plain" || len(plain.Metadata()) != 0 {
+		t.Error("unexpected plain message", plain, err)
+	}
+}
+
+func TestListenMetadata(t *testing.T) {
+	r := New()
+	g := grav.New()
+
+	r.HandleMsg(g.Connect(), "reactr.testmeta", metaRunner{})
+
+	replies := make(chan grav.Message, 1)
+
+	sender := g.Connect()
+	sender.OnType(MsgTypeReactrResult, func(msg grav.Message) error {
+		replies <- msg
+		return nil
+	})
+
+	sender.Send(NewMetaMsg("reactr.testmeta", []byte("hi"), map[string]string{"tenant": "abc"}))
+
+	select {
+	case reply := <-replies:
+		if string(reply.Data()) != "abc:abc:" {
+			t.Error("expected job to receive metadata, got", string(reply.Data()))
+		}
+
+		metaReply, isMetaReply := reply.(MetadataMessage)
+		if !isMetaReply || metaReply.Metadata()["tenant"] != "abc" {
+			t.Error("expected reply with metadata, got", reply)
+		}
+	case <-time.After(time.Second * 2):
+		t.Error("timed out waiting for reply")
+	}
+}
@@ -0,0 +1,17 @@
+package rt
+
+// RunFunc is a function that runs a job, matching the signature of Runnable's Run method
+type RunFunc func(Job, *Ctx) (interface{}, error)
+
+// Middleware wraps a RunFunc to add behaviour to every job run by a Runnable. A Middleware can
+// short-circuit by returning without calling next, pass a modified Job to next, or modify the result that next returns.
+type Middleware func(next RunFunc) RunFunc
+
+// chain wraps run with the provided middleware, the first middleware being the outermost
+func chain(run RunFunc, middleware ...Middleware) RunFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		run = middleware[i](run)
+	}
+
+	return run
+}
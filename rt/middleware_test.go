@@ -0,0 +1,70 @@
+package rt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	h := New()
+
+	order := []string{}
+
+	tag := func(name string) Middleware {
+		return func(next RunFunc) RunFunc {
+			return func(job Job, ctx *Ctx) (interface{}, error) {
+				order = append(order, name)
+				return next(job, ctx)
+			}
+		}
+	}
+
+	h.Use(tag("global"))
+	doGeneric := h.Handle("generic", generic{}, UseMiddleware(tag("handler")))
+
+	if _, err := doGeneric("hello").Then(); err != nil {
+		t.Error(errors.Wrap(err, "failed to Then"))
+		return
+	}
+
+	if len(order) != 2 || order[0] != "global" || order[1] != "handler" {
+		t.Error("unexpected middleware order", order)
+	}
+}
+
+func TestMiddlewareModify(t *testing.T) {
+	h := New()
+
+	h.Use(func(next RunFunc) RunFunc {
+		return func(job Job, ctx *Ctx) (interface{}, error) {
+			if job.String() == "forbidden" {
+				return nil, errors.New("not allowed")
+			}
+
+			res, err := next(job.WithData(fmt.Sprintf("modified %s", job.String())), ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			return fmt.Sprintf("%s!", res), nil
+		}
+	})
+
+	doGeneric := h.Handle("generic", generic{})
+
+	res, err := doGeneric("hello").Then()
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to Then"))
+		return
+	}
+
+	if res.(string) != "modified hello!" {
+		t.Error("expected 'modified hello!', got", res)
+	}
+
+	if _, err := doGeneric("forbidden").Then(); err == nil || err.Error() != "not allowed" {
+		t.Error("expected middleware to short-circuit, got", err)
+	}
+}
@@ -0,0 +1,283 @@
+package rt
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CacheNamespaceShared is the namespace used by Runnables that opt in to the shared cache using the SharedCache option
+const CacheNamespaceShared = ""
+
+// ErrCacheQuotaExceeded is returned when a write would cause a cache namespace to exceed its quota
+var ErrCacheQuotaExceeded = errors.New("cache namespace quota exceeded")
+
+// cacheNamespace scopes all keys written to a Cache under a namespace such that
+// Runnables (or bundles) using different namespaces cannot access each other's data
+type cacheNamespace struct {
+	cache  Cache
+	prefix string
+
+	maxKeys  int
+	maxBytes int
+
+	usage map[string]nsUsage
+	bytes int
+
+	lock sync.Mutex
+}
+
+// nsUsage tracks the size and expiry of a key written through a namespace so that quotas can be enforced
+type nsUsage struct {
+	size    int
+	expires time.Time
+}
+
+func newCacheNamespace(cache Cache, namespace string) *cacheNamespace {
+	c := &cacheNamespace{
+		cache:  cache,
+		prefix: namespace + "\x00", // the null byte ensures one namespace cannot be a prefix of another
+		usage:  map[string]nsUsage{},
+		lock:   sync.Mutex{},
+	}
+
+	return c
+}
+
+// setQuota sets the maximum number of keys and bytes (keys plus values) for the namespace, 0 means unlimited
+func (c *cacheNamespace) setQuota(maxKeys, maxBytes int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.maxKeys = maxKeys
+	c.maxBytes = maxBytes
+}
+
+func (c *cacheNamespace) Set(key string, val []byte, ttl int) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.checkQuota(key, len(val)); err != nil {
+		return err
+	}
+
+	if err := c.cache.Set(c.prefix+key, val, ttl); err != nil {
+		return err
+	}
+
+	c.track(key, len(val), ttl)
+
+	return nil
+}
+
+func (c *cacheNamespace) Get(key string) ([]byte, error) {
+	return c.cache.Get(c.prefix + key)
+}
+
+func (c *cacheNamespace) Delete(key string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.cache.Delete(c.prefix + key); err != nil {
+		return err
+	}
+
+	c.untrack(key)
+
+	return nil
+}
+
+func (c *cacheNamespace) Increment(key string, delta int64) (int64, error) {
+	atomic, ok := c.cache.(AtomicCache)
+	if !ok {
+		return 0, ErrCacheOpNotSupported
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// the size of the new value isn't known until it's been incremented, so only the key count is checked up front
+	if err := c.checkQuota(key, 0); err != nil {
+		return 0, err
+	}
+
+	val, err := atomic.Increment(c.prefix+key, delta)
+	if err != nil {
+		return 0, err
+	}
+
+	// incrementing does not change the key's expiry
+	existing := c.usage[key]
+	c.untrack(key)
+
+	size := len(strconv.FormatInt(val, 10))
+	c.usage[key] = nsUsage{size: size, expires: existing.expires}
+	c.bytes += len(key) + size
+
+	return val, nil
+}
+
+func (c *cacheNamespace) CompareAndSwap(key string, old, new []byte, ttl int) (bool, error) {
+	atomic, ok := c.cache.(AtomicCache)
+	if !ok {
+		return false, ErrCacheOpNotSupported
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.checkQuota(key, len(new)); err != nil {
+		return false, err
+	}
+
+	swapped, err := atomic.CompareAndSwap(c.prefix+key, old, new, ttl)
+	if err != nil || !swapped {
+		return false, err
+	}
+
+	c.track(key, len(new), ttl)
+
+	return true, nil
+}
+
+func (c *cacheNamespace) SetIfAbsent(key string, val []byte, ttl int) (bool, error) {
+	atomic, ok := c.cache.(AtomicCache)
+	if !ok {
+		return false, ErrCacheOpNotSupported
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.checkQuota(key, len(val)); err != nil {
+		return false, err
+	}
+
+	set, err := atomic.SetIfAbsent(c.prefix+key, val, ttl)
+	if err != nil || !set {
+		return false, err
+	}
+
+	c.track(key, len(val), ttl)
+
+	return true, nil
+}
+
+func (c *cacheNamespace) Expire(key string, ttl int) error {
+	expiring, ok := c.cache.(ExpiringCache)
+	if !ok {
+		return ErrCacheOpNotSupported
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := expiring.Expire(c.prefix+key, ttl); err != nil {
+		return err
+	}
+
+	if existing, exists := c.usage[key]; exists {
+		c.track(key, existing.size, ttl)
+	}
+
+	return nil
+}
+
+func (c *cacheNamespace) TTL(key string) (int, error) {
+	expiring, ok := c.cache.(ExpiringCache)
+	if !ok {
+		return 0, ErrCacheOpNotSupported
+	}
+
+	return expiring.TTL(c.prefix + key)
+}
+
+func (c *cacheNamespace) Keys(prefix string) ([]string, error) {
+	enumerable, ok := c.cache.(EnumerableCache)
+	if !ok {
+		return nil, ErrCacheOpNotSupported
+	}
+
+	rawKeys, err := enumerable.Keys(c.prefix + prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(rawKeys))
+	for i, k := range rawKeys {
+		keys[i] = strings.TrimPrefix(k, c.prefix)
+	}
+
+	return keys, nil
+}
+
+// checkQuota determines if writing size bytes to key would exceed the namespace's quota. THIS DOES NOT LOCK. THE CALLER MUST LOCK.
+func (c *cacheNamespace) checkQuota(key string, size int) error {
+	if c.maxKeys == 0 && c.maxBytes == 0 {
+		return nil
+	}
+
+	if c.exceedsQuota(key, size) {
+		// expired keys are only pruned when needed, so check again after doing so
+		c.pruneExpired()
+
+		if c.exceedsQuota(key, size) {
+			return ErrCacheQuotaExceeded
+		}
+	}
+
+	return nil
+}
+
+func (c *cacheNamespace) exceedsQuota(key string, size int) bool {
+	keys := len(c.usage)
+	bytes := c.bytes + len(key) + size
+
+	if existing, exists := c.usage[key]; exists {
+		bytes -= len(key) + existing.size
+	} else {
+		keys++
+	}
+
+	if c.maxKeys > 0 && keys > c.maxKeys {
+		return true
+	}
+
+	if c.maxBytes > 0 && bytes > c.maxBytes {
+		return true
+	}
+
+	return false
+}
+
+func (c *cacheNamespace) pruneExpired() {
+	now := time.Now()
+
+	for key, u := range c.usage {
+		if !u.expires.IsZero() && !now.Before(u.expires) {
+			c.untrack(key)
+		}
+	}
+}
+
+func (c *cacheNamespace) track(key string, size int, ttl int) {
+	c.untrack(key)
+
+	u := nsUsage{size: size}
+	if ttl > 0 {
+		u.expires = time.Now().Add(time.Second * time.Duration(ttl))
+	}
+
+	c.usage[key] = u
+	c.bytes += len(key) + size
+}
+
+func (c *cacheNamespace) untrack(key string) {
+	if existing, exists := c.usage[key]; exists {
+		c.bytes -= len(key) + existing.size
+		delete(c.usage, key)
+	}
+}
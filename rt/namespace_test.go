@@ -0,0 +1,89 @@
+package rt
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCacheNamespaceIsolation(t *testing.T) {
+	h := New()
+	h.Handle("set", &setTester{})
+	h.Handle("get", &getTester{})
+
+	if _, err := h.Do(NewJob("set", "very important information")).Then(); err != nil {
+		t.Error(errors.Wrap(err, "failed to set"))
+		return
+	}
+
+	if _, err := h.Do(NewJob("get", "important")).Then(); err != ErrCacheKeyNotFound {
+		t.Error("expected ErrCacheKeyNotFound from other namespace, got", err)
+	}
+}
+
+func TestCacheNamespaceOption(t *testing.T) {
+	h := New()
+	h.Handle("set", &setTester{}, CacheNamespace("bundle"))
+	h.Handle("get", &getTester{}, CacheNamespace("bundle"))
+	h.Handle("other", &getTester{}, SharedCache())
+
+	if _, err := h.Do(NewJob("set", "very important information")).Then(); err != nil {
+		t.Error(errors.Wrap(err, "failed to set"))
+		return
+	}
+
+	val, err := h.Do(NewJob("get", "important")).Then()
+	if err != nil {
+		t.Error(errors.Wrap(err, "get job failed"))
+		return
+	}
+
+	if val.(string) != "very important information" {
+		t.Error("result did not match expected 'very important information': ", val.(string))
+	}
+
+	if _, err := h.Do(NewJob("other", "important")).Then(); err != ErrCacheKeyNotFound {
+		t.Error("expected ErrCacheKeyNotFound from shared namespace, got", err)
+	}
+}
+
+func TestCacheNamespaceQuota(t *testing.T) {
+	ns := newCacheNamespace(newMemoryCache(), "quota")
+	ns.setQuota(2, 20)
+
+	if err := ns.Set("a", []byte("1"), 0); err != nil {
+		t.Error(errors.Wrap(err, "failed to Set"))
+	}
+
+	if err := ns.Set("b", []byte("2"), 0); err != nil {
+		t.Error(errors.Wrap(err, "failed to Set"))
+	}
+
+	if err := ns.Set("c", []byte("3"), 0); err != ErrCacheQuotaExceeded {
+		t.Error("expected ErrCacheQuotaExceeded for key count, got", err)
+	}
+
+	// overwriting an existing key does not add to the key count
+	if err := ns.Set("b", []byte("22"), 0); err != nil {
+		t.Error(errors.Wrap(err, "failed to overwrite"))
+	}
+
+	if err := ns.Set("b", []byte("this value is too large"), 0); err != ErrCacheQuotaExceeded {
+		t.Error("expected ErrCacheQuotaExceeded for bytes, got", err)
+	}
+
+	if err := ns.Delete("a"); err != nil {
+		t.Error(errors.Wrap(err, "failed to Delete"))
+	}
+
+	if err := ns.Set("c", []byte("3"), 0); err != nil {
+		t.Error(errors.Wrap(err, "failed to Set after Delete"))
+	}
+
+	keys, err := ns.Keys("")
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to Keys"))
+	} else if len(keys) != 2 || keys[0] != "b" || keys[1] != "c" {
+		t.Error("unexpected keys:", keys)
+	}
+}
@@ -1,5 +1,7 @@
 package rt
 
+import "time"
+
 // Option is a function that modifies workerOpts
 type Option func(workerOpts) workerOpts
 
@@ -13,16 +15,26 @@ func PoolSize(size int) Option {
 
 //TimeoutSeconds returns an Option with the job timeout seconds set
 func TimeoutSeconds(timeout int) Option {
+	return Timeout(time.Second * time.Duration(timeout))
+}
+
+// Timeout returns an Option with the job timeout set, which has millisecond resolution
+func Timeout(timeout time.Duration) Option {
 	return func(opts workerOpts) workerOpts {
-		opts.jobTimeoutSeconds = timeout
+		opts.jobTimeout = timeout
 		return opts
 	}
 }
 
 //RetrySeconds returns an Option to set the worker retry seconds
 func RetrySeconds(secs int) Option {
+	return RetryDelay(time.Second * time.Duration(secs))
+}
+
+// RetryDelay returns an Option to set the delay between attempts to start the worker
+func RetryDelay(delay time.Duration) Option {
 	return func(opts workerOpts) workerOpts {
-		opts.retrySecs = secs
+		opts.retryDelay = delay
 		return opts
 	}
 }
@@ -43,3 +55,82 @@ func PreWarm() Option {
 		return opts
 	}
 }
+
+// CacheNamespace sets the namespace that the Runnable's cache is scoped to.
+// By default, each Runnable's cache is scoped to a namespace matching its job type.
+// Runnables registered with the same namespace can access each other's cache keys.
+func CacheNamespace(namespace string) Option {
+	return func(opts workerOpts) workerOpts {
+		opts.cacheNamespace = namespace
+		return opts
+	}
+}
+
+// SharedCache causes the Runnable's cache to use the shared namespace rather than its own,
+// allowing it to access keys set by any other Runnable that has also opted in.
+func SharedCache() Option {
+	return func(opts workerOpts) workerOpts {
+		opts.sharedCache = true
+		return opts
+	}
+}
+
+// CacheQuota sets the maximum number of keys and bytes (keys plus values) that can be stored in the Runnable's cache namespace.
+// The quota applies to the namespace as a whole, and so is shared by all Runnables using it. Pass 0 for no limit.
+func CacheQuota(maxKeys, maxBytes int) Option {
+	return func(opts workerOpts) workerOpts {
+		opts.cacheMaxKeys = maxKeys
+		opts.cacheMaxBytes = maxBytes
+		return opts
+	}
+}
+
+// OverflowToPeers allows jobs to be forwarded to a peer Reactr instance (see Reactr.Federate) when the number
+// of jobs waiting in the worker's queue reaches depth. Jobs are only forwarded if a peer has the same handler registered.
+func OverflowToPeers(depth int) Option {
+	return func(opts workerOpts) workerOpts {
+		opts.overflowDepth = depth
+		return opts
+	}
+}
+
+// UseMiddleware returns an Option that wraps the Runnable's Run function with the provided Middleware.
+// Middleware is applied in order, the first being the outermost.
+func UseMiddleware(middleware ...Middleware) Option {
+	return func(opts workerOpts) workerOpts {
+		opts.middleware = append(opts.middleware, middleware...)
+		return opts
+	}
+}
+
+// PartitionByKey causes jobs with the same partition key (see Job.WithPartitionKey) to always be run by the same
+// workThread in the order they were scheduled, guaranteeing per-key ordering while still using the whole pool.
+// Jobs without a partition key are run by any available workThread.
+func PartitionByKey() Option {
+	return func(opts workerOpts) workerOpts {
+		opts.partitioned = true
+		return opts
+	}
+}
+
+// ResourcePool binds the handler to a named pool of resource slots shared with any other handler bound to the same
+// name, such that no more than size jobs from all of those handlers run at once (regardless of their PoolSize).
+// This bounds the total concurrency against a shared dependency such as a database. If handlers declare
+// different sizes for the same pool, the most recently registered size is used.
+func ResourcePool(name string, size int) Option {
+	return func(opts workerOpts) workerOpts {
+		opts.resourcePool = name
+		opts.resourcePoolSize = size
+		return opts
+	}
+}
+
+// OnStopped returns an Option that sets a function to be called once the handler has been replaced or unhandled,
+// its in-flight jobs have completed, and its Runnable has been stopped. This allows resources that the Runnable
+// depends on to be released only once nothing is using them.
+func OnStopped(fn func()) Option {
+	return func(opts workerOpts) workerOpts {
+		opts.onStopped = fn
+		return opts
+	}
+}
@@ -0,0 +1,68 @@
+package rt
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// jobQueue is an unbounded FIFO queue of jobs for a single workThread, used to keep jobs with the same partition key in order
+type jobQueue struct {
+	jobs  []JobReference
+	ready chan struct{}
+	lock  sync.Mutex
+}
+
+func newJobQueue() *jobQueue {
+	q := &jobQueue{
+		jobs:  []JobReference{},
+		ready: make(chan struct{}, 1),
+		lock:  sync.Mutex{},
+	}
+
+	return q
+}
+
+// push adds a job to the end of the queue and signals that the queue is ready
+func (q *jobQueue) push(job JobReference) {
+	q.lock.Lock()
+	q.jobs = append(q.jobs, job)
+	q.lock.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+		// the queue has already been signalled
+	}
+}
+
+// pop removes the job at the front of the queue, returning false if the queue is empty
+func (q *jobQueue) pop() (JobReference, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.jobs) == 0 {
+		return JobReference{}, false
+	}
+
+	job := q.jobs[0]
+	q.jobs = q.jobs[1:]
+
+	return job, true
+}
+
+// partitionFor returns the index of the bucket (out of numBuckets) that the key belongs to, using
+// jump consistent hashing (Lamping & Veach) so that few keys move if the number of buckets changes
+func partitionFor(key string, numBuckets int) int {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(key))
+	hash := hasher.Sum64()
+
+	b, j := int64(-1), int64(0)
+	for j < int64(numBuckets) {
+		b = j
+		hash = hash*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((hash>>33)+1)))
+	}
+
+	return int(b)
+}
@@ -0,0 +1,197 @@
+package rt
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type orderRunner struct {
+	last   map[string]int
+	active map[string]bool
+	lock   sync.Mutex
+}
+
+func (o *orderRunner) Run(job Job, ctx *Ctx) (interface{}, error) {
+	key := job.PartitionKey()
+	seq := job.Int()
+
+	o.lock.Lock()
+	if o.active[key] {
+		o.lock.Unlock()
+		return nil, fmt.Errorf("job %d for key %s ran concurrently with another", seq, key)
+	}
+
+	if last, exists := o.last[key]; exists && last != seq-1 {
+		o.lock.Unlock()
+		return nil, fmt.Errorf("job %d for key %s ran after job %d", seq, key, last)
+	}
+
+	o.active[key] = true
+	o.last[key] = seq
+	o.lock.Unlock()
+
+	time.Sleep(time.Millisecond * time.Duration(seq%3))
+
+	o.lock.Lock()
+	o.active[key] = false
+	o.lock.Unlock()
+
+	return nil, nil
+}
+
+func (o *orderRunner) OnChange(_ ChangeEvent) error { return nil }
+
+func TestPartitionByKey(t *testing.T) {
+	r := New()
+
+	runner := &orderRunner{last: map[string]int{}, active: map[string]bool{}}
+	r.Handle("order", runner, PoolSize(4), PartitionByKey())
+
+	grp := NewGroup()
+
+	for i := 0; i < 50; i++ {
+		for _, key := range []string{"a", "b", "c", "d", "e"} {
+			grp.Add(r.Do(NewJob("order", i).WithPartitionKey(key)))
+		}
+	}
+
+	// jobs without a key can run on any thread
+	grp.Add(r.Do(NewJob("order", 0)))
+
+	if err := grp.Wait(); err != nil {
+		t.Error(errors.Wrap(err, "jobs were not run in order"))
+	}
+}
+
+func TestPartitionStartInBackground(t *testing.T) {
+	r := New()
+
+	runner := &slowStartRunner{release: make(chan struct{})}
+	r.Handle("slow", runner, PoolSize(2), PartitionByKey())
+
+	// the jobs are queued without waiting for the worker to start
+	done := make(chan []*Result)
+	go func() {
+		done <- []*Result{r.Do(NewJob("slow", 1).WithPartitionKey("a")), r.Do(NewJob("slow", 2).WithPartitionKey("a"))}
+	}()
+
+	var results []*Result
+
+	select {
+	case results = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Do blocked while the worker was starting")
+	}
+
+	close(runner.release)
+
+	for _, res := range results {
+		if _, err := res.Then(); err != nil {
+			t.Error(errors.Wrap(err, "failed to Then"))
+		}
+	}
+
+	// a worker that fails to start fails its queued jobs rather than leaving them waiting
+	r.Handle("bad", badRunner{}, PoolSize(2), PartitionByKey(), RetryDelay(time.Millisecond), MaxRetries(1))
+
+	if _, err := r.Do(NewJob("bad", nil).WithPartitionKey("a")).Then(); err == nil {
+		t.Error("expected error, did not get one")
+	}
+}
+
+// partialStartRunner allows its first starts to succeed, and fails every start after that
+type partialStartRunner struct {
+	allowed int32
+	starts  int32
+	stops   int32
+}
+
+func (p *partialStartRunner) Run(job Job, ctx *Ctx) (interface{}, error) {
+	return nil, nil
+}
+
+func (p *partialStartRunner) OnChange(change ChangeEvent) error {
+	switch change {
+	case ChangeTypeStart:
+		if atomic.AddInt32(&p.starts, 1) > p.allowed {
+			return errors.New("fail")
+		}
+	case ChangeTypeStop:
+		atomic.AddInt32(&p.stops, 1)
+	}
+
+	return nil
+}
+
+func TestPartitionPartialStart(t *testing.T) {
+	r := New()
+
+	runner := &partialStartRunner{allowed: 2}
+	r.Handle("partial", runner, PoolSize(4), PartitionByKey(), RetryDelay(time.Millisecond), MaxRetries(1))
+
+	grp := NewGroup()
+	for i := 0; i < 20; i++ {
+		grp.Add(r.Do(NewJob("partial", i).WithPartitionKey(fmt.Sprintf("key-%d", i))))
+	}
+
+	// every job must complete or fail, including those hashed to a partition whose workThread failed to start
+	done := make(chan error)
+	go func() {
+		done <- grp.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected error, did not get one")
+		}
+	case <-time.After(time.Second * 2):
+		t.Error("jobs were left waiting by a partially started worker")
+		return
+	}
+
+	// the workThreads that did start are stopped along with the rest
+	if stops := atomic.LoadInt32(&runner.stops); stops != 2 {
+		t.Errorf("expected 2 workThreads to be stopped, got %d", stops)
+	}
+
+	unhandled := make(chan error)
+	go func() {
+		unhandled <- r.Unhandle("partial")
+	}()
+
+	select {
+	case err := <-unhandled:
+		if err != nil {
+			t.Error(errors.Wrap(err, "failed to Unhandle"))
+		}
+	case <-time.After(time.Second * 2):
+		t.Error("Unhandle blocked on a partially started worker")
+	}
+}
+
+func TestPartitionFor(t *testing.T) {
+	counts := make([]int, 4)
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+
+		p := partitionFor(key, 4)
+		if p != partitionFor(key, 4) {
+			t.Fatalf("partition for %s is not stable", key)
+		}
+
+		counts[p]++
+	}
+
+	for i, c := range counts {
+		if c == 0 {
+			t.Errorf("no keys were assigned to partition %d", i)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package rt
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/suborbital/grav/grav"
+	"github.com/suborbital/vektor/vlog"
+)
+
+// MsgTypeReactrPeerAdvert and others are Grav message types used to distribute jobs between Reactr instances
+const (
+	MsgTypeReactrPeerAdvert = "reactr.peer.advert"
+	MsgTypeReactrPeerJob    = "reactr.peer.job"
+	MsgTypeReactrPeerResult = "reactr.peer.result"
+)
+
+// ErrPeerLost and others are returned when a forwarded job's peer doesn't return its result
+var (
+	ErrPeerLost          = errors.New("peer was lost before returning a result")
+	ErrPeerTimeout       = errors.New("peer did not return a result in time")
+	ErrFederationStopped = errors.New("federation was stopped before the peer returned a result")
+)
+
+const (
+	peerAdvertInterval = time.Second * 2
+	peerExpiryInterval = peerAdvertInterval * 3
+
+	// how long a forwarded job without a deadline waits for its result
+	peerJobTimeout = time.Minute
+)
+
+// peerAdvert is sent periodically by each Reactr to announce the job types it can handle
+type peerAdvert struct {
+	ID       string         `json:"id"`
+	JobTypes map[string]int `json:"jobTypes"` // the value is the queue depth (i.e. load) of each job type
+}
+
+// peerJob is a job that has been forwarded to a particular peer
+type peerJob struct {
+	Peer    string            `json:"peer"`
+	JobType string            `json:"jobType"`
+	Data    []byte            `json:"data"`
+	Meta    map[string]string `json:"meta,omitempty"`
+
+	// PartitionKey is the job's partition key, so that the peer keeps jobs with the same key in order
+	PartitionKey string `json:"partitionKey,omitempty"`
+
+	// the job's deadline as a unix timestamp in nanoseconds, or 0 if it has none
+	Deadline int64 `json:"deadline,omitempty"`
+}
+
+// peerResult is a peer's response to a peerJob
+type peerResult struct {
+	Data  []byte `json:"data,omitempty"`
+	Nil   bool   `json:"nil,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type peerState struct {
+	jobTypes map[string]int
+	lastSeen time.Time
+}
+
+type pendingPeerJob struct {
+	peer   string
+	result *Result
+
+	// closed once the job is no longer pending
+	done chan struct{}
+}
+
+// peerRouter advertises local capabilities to peers over Grav and forwards jobs to them
+type peerRouter struct {
+	id        string
+	pod       *grav.Pod
+	scheduler *scheduler
+	log       *vlog.Logger
+
+	peers   map[string]*peerState
+	pending map[string]pendingPeerJob
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+
+	lock sync.Mutex
+}
+
+func newPeerRouter(pod *grav.Pod, scheduler *scheduler, log *vlog.Logger) *peerRouter {
+	p := &peerRouter{
+		id:        uuid.New().String(),
+		pod:       pod,
+		scheduler: scheduler,
+		log:       log,
+		peers:     map[string]*peerState{},
+		pending:   map[string]pendingPeerJob{},
+		stopChan:  make(chan struct{}),
+		lock:      sync.Mutex{},
+	}
+
+	return p
+}
+
+// start begins listening for peer messages and advertising on a regular interval
+func (p *peerRouter) start() {
+	p.pod.On(p.onMsg)
+
+	go func() {
+		ticker := time.NewTicker(peerAdvertInterval)
+		defer ticker.Stop()
+
+		for {
+			p.advertise()
+			p.pruneExpired()
+
+			select {
+			case <-ticker.C:
+			case <-p.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// stop stops advertising and handling peer messages, and fails any jobs still waiting for a peer
+func (p *peerRouter) stop() {
+	p.stopOnce.Do(func() {
+		close(p.stopChan)
+
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		for msgUUID, pending := range p.pending {
+			p.removePending(msgUUID, pending)
+			pending.result.sendErr(ErrFederationStopped)
+		}
+	})
+}
+
+func (p *peerRouter) isStopped() bool {
+	select {
+	case <-p.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// forward sends the job to the least loaded peer that can handle it.
+// if no peer can handle the job, nil is returned so that it can be handled locally
+func (p *peerRouter) forward(job Job) *Result {
+	peerID := p.choosePeer(job.jobType)
+	if peerID == "" {
+		return nil
+	}
+
+	result := newResult(job.UUID(), func(_ string) {})
+
+	data, err := toBytes(job.data)
+	if err != nil {
+		result.sendErr(errors.Wrap(err, "failed to convert job data to bytes for peer"))
+		return result
+	}
+
+	pj := peerJob{
+		Peer:    peerID,
+		JobType: job.jobType,
+		Data:    data,
+		Meta:    job.meta,
+
+		PartitionKey: job.partitionKey,
+	}
+
+	if !job.deadline.IsZero() {
+		pj.Deadline = job.deadline.UnixNano()
+	}
+
+	pjJSON, err := json.Marshal(pj)
+	if err != nil {
+		result.sendErr(errors.Wrap(err, "failed to Marshal peer job"))
+		return result
+	}
+
+	msg := grav.NewMsg(MsgTypeReactrPeerJob, pjJSON)
+
+	pending := pendingPeerJob{peer: peerID, result: result, done: make(chan struct{})}
+
+	p.lock.Lock()
+	p.pending[msg.UUID()] = pending
+	p.lock.Unlock()
+
+	// the job fails if the peer doesn't return a result by its deadline, or within peerJobTimeout if it has none
+	wait, waitErr := peerJobTimeout, ErrPeerTimeout
+	if !job.deadline.IsZero() {
+		wait, waitErr = job.deadline.Sub(p.scheduler.opts.clock.Now()), ErrJobDeadlineExceeded
+	}
+
+	go p.expire(msg.UUID(), pending, wait, waitErr)
+
+	p.pod.Send(msg)
+
+	return result
+}
+
+// expire fails the pending job with err if it is still pending once wait has passed
+func (p *peerRouter) expire(msgUUID string, pending pendingPeerJob, wait time.Duration, err error) {
+	select {
+	case <-p.scheduler.opts.clock.After(wait):
+	case <-pending.done:
+		return
+	}
+
+	if _, exists := p.takePending(msgUUID); exists {
+		pending.result.sendErr(err)
+	}
+}
+
+// takePending removes and returns the pending job for the message, if it is still pending
+func (p *peerRouter) takePending(msgUUID string) (pendingPeerJob, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pending, exists := p.pending[msgUUID]
+	if exists {
+		p.removePending(msgUUID, pending)
+	}
+
+	return pending, exists
+}
+
+// removePending removes a pending job. THIS DOES NOT LOCK. THE CALLER MUST LOCK.
+func (p *peerRouter) removePending(msgUUID string, pending pendingPeerJob) {
+	delete(p.pending, msgUUID)
+	close(pending.done)
+}
+
+// choosePeer returns the ID of the peer with the lowest load for the given job type, or an empty string if there is none
+func (p *peerRouter) choosePeer(jobType string) string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	chosen := ""
+	lowest := -1
+
+	for id, peer := range p.peers {
+		load, handles := peer.jobTypes[jobType]
+		if !handles {
+			continue
+		}
+
+		if lowest == -1 || load < lowest {
+			chosen = id
+			lowest = load
+		}
+	}
+
+	return chosen
+}
+
+func (p *peerRouter) onMsg(msg grav.Message) error {
+	if p.isStopped() {
+		return nil
+	}
+
+	switch msg.Type() {
+	case MsgTypeReactrPeerAdvert:
+		p.handleAdvert(msg)
+	case MsgTypeReactrPeerJob:
+		p.handleJob(msg)
+	case MsgTypeReactrPeerResult:
+		p.handleResult(msg)
+	}
+
+	return nil
+}
+
+func (p *peerRouter) handleAdvert(msg grav.Message) {
+	advert := peerAdvert{}
+	if err := msg.UnmarshalData(&advert); err != nil {
+		p.log.Error(errors.Wrap(err, "failed to UnmarshalData peer advert"))
+		return
+	}
+
+	if advert.ID == p.id {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.peers[advert.ID] = &peerState{
+		jobTypes: advert.JobTypes,
+		lastSeen: p.scheduler.opts.clock.Now(),
+	}
+}
+
+func (p *peerRouter) handleJob(msg grav.Message) {
+	pj := peerJob{}
+	if err := msg.UnmarshalData(&pj); err != nil {
+		p.log.Error(errors.Wrap(err, "failed to UnmarshalData peer job"))
+		return
+	}
+
+	// jobs are addressed to a single peer, ignore any meant for someone else
+	if pj.Peer != p.id {
+		return
+	}
+
+	job := NewJob(pj.JobType, pj.Data).WithPartitionKey(pj.PartitionKey)
+	job.meta = pj.Meta
+
+	if pj.Deadline != 0 {
+		job.deadline = time.Unix(0, pj.Deadline)
+	}
+
+	// the job must be run locally, otherwise it could bounce between peers forever
+	res, err := p.scheduler.scheduleLocal(job, p.scheduler.getWorker(pj.JobType)).Then()
+
+	pr := peerResult{}
+
+	if err != nil {
+		pr.Error = err.Error()
+	} else if res == nil {
+		pr.Nil = true
+	} else if bytes, bytesErr := toBytes(res); bytesErr != nil {
+		pr.Error = errors.Wrap(bytesErr, "failed to convert job result to bytes").Error()
+	} else {
+		pr.Data = bytes
+	}
+
+	prJSON, err := json.Marshal(pr)
+	if err != nil {
+		p.log.Error(errors.Wrap(err, "failed to Marshal peer result"))
+		return
+	}
+
+	p.pod.ReplyTo(msg, grav.NewMsg(MsgTypeReactrPeerResult, prJSON))
+}
+
+func (p *peerRouter) handleResult(msg grav.Message) {
+	pending, exists := p.takePending(msg.ReplyTo())
+	if !exists {
+		return
+	}
+
+	pr := peerResult{}
+	if err := msg.UnmarshalData(&pr); err != nil {
+		pending.result.sendErr(errors.Wrap(err, "failed to UnmarshalData peer result"))
+		return
+	}
+
+	if pr.Error != "" {
+		pending.result.sendErr(errors.New(pr.Error))
+	} else if pr.Nil {
+		pending.result.sendResult(nil)
+	} else {
+		pending.result.sendResult(pr.Data)
+	}
+}
+
+func (p *peerRouter) advertise() {
+	advert := peerAdvert{
+		ID:       p.id,
+		JobTypes: p.scheduler.jobTypes(),
+	}
+
+	advertJSON, err := json.Marshal(advert)
+	if err != nil {
+		p.log.Error(errors.Wrap(err, "failed to Marshal peer advert"))
+		return
+	}
+
+	p.pod.Send(grav.NewMsg(MsgTypeReactrPeerAdvert, advertJSON))
+}
+
+// pruneExpired removes peers that have stopped advertising and fails any jobs that were forwarded to them
+func (p *peerRouter) pruneExpired() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for id, peer := range p.peers {
+		if p.scheduler.opts.clock.Now().Sub(peer.lastSeen) < peerExpiryInterval {
+			continue
+		}
+
+		delete(p.peers, id)
+
+		for msgUUID, pending := range p.pending {
+			if pending.peer == id {
+				p.removePending(msgUUID, pending)
+				pending.result.sendErr(ErrPeerLost)
+			}
+		}
+	}
+}
+
+// toBytes converts job data or a job result into bytes so that it can be sent over the wire
+func toBytes(data interface{}) ([]byte, error) {
+	if data == nil {
+		return nil, nil
+	} else if msg, isMsg := data.(grav.Message); isMsg {
+		return msg.Data(), nil
+	} else if bytes, isBytes := data.([]byte); isBytes {
+		return bytes, nil
+	} else if str, isString := data.(string); isString {
+		return []byte(str), nil
+	}
+
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to Marshal")
+	}
+
+	return dataJSON, nil
+}
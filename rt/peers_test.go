@@ -0,0 +1,215 @@
+package rt
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/grav/grav"
+)
+
+func TestFederateForwardsToPeer(t *testing.T) {
+	g := grav.New()
+
+	local := New()
+	local.Federate(g.Connect())
+
+	remote := New()
+	remote.Handle("generic", generic{})
+	remote.Federate(g.Connect())
+
+	// wait for the adverts to be exchanged
+	<-time.After(time.Millisecond * 100)
+
+	res, err := local.Do(NewJob("generic", "first")).Then()
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to Then"))
+		return
+	}
+
+	if string(res.([]byte)) != "last" {
+		t.Error("expected 'last', got", string(res.([]byte)))
+	}
+
+	if _, err := local.Do(NewJob("generic", "fail")).Then(); err == nil || err.Error() != "error" {
+		t.Error("expected remote error to be propogated, got", err)
+	}
+
+	if res, err := local.Do(NewJob("nil", "")).Then(); err == nil {
+		t.Error("expected error for job type with no handler, got", res)
+	}
+}
+
+func TestFederateOverflow(t *testing.T) {
+	g := grav.New()
+
+	local := New()
+	local.Handle("timeout", timeoutRunner{}, OverflowToPeers(1))
+	local.Federate(g.Connect())
+
+	remote := New()
+	remote.Handle("timeout", generic{})
+	remote.Federate(g.Connect())
+
+	<-time.After(time.Millisecond * 100)
+
+	// the first job occupies the local worker, the second waits in its queue, and the third should overflow to the peer
+	local.Do(NewJob("timeout", "one")).Discard()
+	local.Do(NewJob("timeout", "two")).Discard()
+
+	<-time.After(time.Millisecond * 100)
+
+	res, err := local.Do(NewJob("timeout", "three")).Then()
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to Then"))
+		return
+	}
+
+	if string(res.([]byte)) != "three" {
+		t.Error("expected 'three' from peer, got", res)
+	}
+}
+
+// advertiseSilentPeer connects a peer that advertises a job type but never returns results
+func advertiseSilentPeer(t *testing.T, g *grav.Grav, jobType string) {
+	advertJSON, err := json.Marshal(peerAdvert{ID: "silent", JobTypes: map[string]int{jobType: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.Connect().Send(grav.NewMsg(MsgTypeReactrPeerAdvert, advertJSON))
+
+	// wait for the advert to be received
+	<-time.After(time.Millisecond * 100)
+}
+
+func TestFederatePendingExpires(t *testing.T) {
+	g := grav.New()
+
+	local := New()
+	local.Federate(g.Connect())
+
+	advertiseSilentPeer(t, g, "silent")
+
+	_, err := local.Do(NewJob("silent", "hi").WithDeadline(time.Now().Add(time.Millisecond * 100))).Then()
+	if err != ErrJobDeadlineExceeded {
+		t.Error("expected ErrJobDeadlineExceeded, got", err)
+	}
+}
+
+func TestUnfederate(t *testing.T) {
+	g := grav.New()
+
+	local := New()
+	local.Federate(g.Connect())
+
+	// federating again replaces the first router rather than running both
+	local.Federate(g.Connect())
+
+	advertiseSilentPeer(t, g, "silent")
+
+	res := local.Do(NewJob("silent", "hi"))
+
+	local.Unfederate()
+
+	if _, err := res.Then(); err != ErrFederationStopped {
+		t.Error("expected ErrFederationStopped, got", err)
+	}
+
+	// jobs are no longer forwarded
+	if _, err := local.Do(NewJob("silent", "hi")).Then(); err == nil || err == ErrFederationStopped {
+		t.Error("expected job to fail locally, got", err)
+	}
+}
+
+// offsetClock is the system clock moved forward by offset
+type offsetClock struct {
+	offset time.Duration
+	lock   sync.Mutex
+}
+
+func (o *offsetClock) Now() time.Time {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	return time.Now().Add(o.offset)
+}
+
+func (o *offsetClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (o *offsetClock) advance(d time.Duration) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.offset += d
+}
+
+func TestFederatePeerExpiresByClock(t *testing.T) {
+	g := grav.New()
+
+	clock := &offsetClock{}
+
+	local := New(UseClock(clock))
+	local.Federate(g.Connect())
+
+	advertiseSilentPeer(t, g, "silent")
+
+	res := local.Do(NewJob("silent", "hi"))
+
+	// peers are expired using the Reactr's clock rather than the wall clock
+	local.scheduler.getRouter().pruneExpired()
+
+	clock.advance(peerExpiryInterval)
+	local.scheduler.getRouter().pruneExpired()
+
+	errChan := make(chan error)
+	go func() {
+		_, err := res.Then()
+		errChan <- err
+	}()
+
+	select {
+	case err := <-errChan:
+		if err != ErrPeerLost {
+			t.Error("expected ErrPeerLost, got", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the peer to have expired")
+	}
+}
+
+// keyRunner returns the partition key of its job
+type keyRunner struct{}
+
+func (k keyRunner) Run(job Job, ctx *Ctx) (interface{}, error) {
+	return job.PartitionKey(), nil
+}
+
+func (k keyRunner) OnChange(_ ChangeEvent) error { return nil }
+
+func TestFederateForwardsPartitionKey(t *testing.T) {
+	g := grav.New()
+
+	local := New()
+	local.Federate(g.Connect())
+
+	remote := New()
+	remote.Handle("key", keyRunner{}, PartitionByKey())
+	remote.Federate(g.Connect())
+
+	<-time.After(time.Millisecond * 100)
+
+	res, err := local.Do(NewJob("key", nil).WithPartitionKey("abc")).Then()
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to Then"))
+		return
+	}
+
+	if string(res.([]byte)) != "abc" {
+		t.Error("expected 'abc', got", string(res.([]byte)))
+	}
+}
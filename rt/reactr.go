@@ -21,16 +21,23 @@ type JobFunc func(interface{}) *Result
 // Reactr represents the main control object
 type Reactr struct {
 	scheduler *scheduler
+	remotes   *remoteClients
 	log       *vlog.Logger
 }
 
 // New returns a Reactr ready to accept Jobs
-func New() *Reactr {
+func New(options ...ReactrOption) *Reactr {
+	opts := defaultReactrOpts()
+	for _, o := range options {
+		opts = o(opts)
+	}
+
 	logger := vlog.Default()
 	cache := newMemoryCache()
 
 	h := &Reactr{
-		scheduler: newScheduler(logger, cache),
+		scheduler: newScheduler(logger, cache, opts),
+		remotes:   newRemoteClients(),
 		log:       logger,
 	}
 
@@ -61,6 +68,42 @@ func (h *Reactr) Handle(jobType string, runner Runnable, options ...Option) JobF
 	return helper
 }
 
+// Unhandle removes the handler for jobType. Jobs that were already scheduled are allowed to complete,
+// and then the handler's Runnable is stopped. Any jobs of that type scheduled afterwards will fail.
+func (h *Reactr) Unhandle(jobType string) error {
+	if err := h.scheduler.unhandle(jobType); err != nil {
+		return errors.Wrapf(err, "failed to unhandle %s", jobType)
+	}
+
+	return nil
+}
+
+// Replace atomically switches the handler for jobType to a new Runnable. Jobs scheduled after Replace
+// returns are handled by the new Runnable, while the old one finishes its in-flight jobs in the background and is then stopped.
+func (h *Reactr) Replace(jobType string, runner Runnable, options ...Option) error {
+	if err := h.scheduler.replace(jobType, runner, options...); err != nil {
+		return errors.Wrapf(err, "failed to replace %s", jobType)
+	}
+
+	return nil
+}
+
+// Handlers returns a snapshot of each registered handler's options and state, sorted by job type
+func (h *Reactr) Handlers() []HandlerInfo {
+	return h.scheduler.handlers()
+}
+
+// Schedules returns a snapshot of each Schedule that is being watched, sorted by ID
+func (h *Reactr) Schedules() []ScheduleInfo {
+	return h.scheduler.schedules()
+}
+
+// Use adds Middleware that wraps the Run function of every Runnable registered with the Reactr, including Wasm Runnables.
+// Middleware added with Use runs before any added for a particular handler with the UseMiddleware option.
+func (h *Reactr) Use(middleware ...Middleware) {
+	h.scheduler.use(middleware...)
+}
+
 // HandleMsg registers a Runnable with the Reactr and triggers that job whenever the provided Grav pod
 // receives a message of a particular type.
 func (h *Reactr) HandleMsg(pod *grav.Pod, msgType string, runner Runnable, options ...Option) {
@@ -71,45 +114,41 @@ func (h *Reactr) HandleMsg(pod *grav.Pod, msgType string, runner Runnable, optio
 
 // Listen causes Reactr to listen for messages of the given type and trigger the job of the same type.
 // The message's data is passed to the runnable as the job data.
-// The job's result is then emitted as a message. If an error occurs, it is logged and an error is sent.
-// If the result is nil, nothing is sent.
-func (h *Reactr) Listen(pod *grav.Pod, msgType string) {
-	helper := func(data interface{}) *Result {
-		job := NewJob(msgType, data)
-
-		return h.Do(job)
+// The job's result is then emitted as a message using the configured MessageEncoder (DefaultMessageEncoder if not set).
+// If an error occurs, it is logged and a reactr.joberr message containing a JSON-encoded JobError is sent.
+// If the result is nil, a reactr.nil message is sent.
+func (h *Reactr) Listen(pod *grav.Pod, msgType string, options ...ListenOption) {
+	opts := defaultListenOpts()
+	for _, o := range options {
+		opts = o(opts)
 	}
 
 	pod.OnType(msgType, func(msg grav.Message) error {
-		var replyMsg grav.Message
-
-		result, err := helper(msg.Data()).Then()
-		if err != nil {
-			h.log.Error(errors.Wrapf(err, "job from message %s returned error result", msg.UUID()))
-			replyMsg = grav.NewMsg(MsgTypeReactrJobErr, []byte(err.Error()))
-		} else {
-			if result == nil {
-				// if the job returned no result
-				replyMsg = grav.NewMsg(MsgTypeReactrNilResult, []byte{})
-			} else if resultMsg, isMsg := result.(grav.Message); isMsg {
-				// if the job returned a Grav message
-				resultMsg.SetReplyTo(msg.UUID())
-				replyMsg = resultMsg
-			} else if bytes, isBytes := result.([]byte); isBytes {
-				// if the job returned bytes
-				replyMsg = grav.NewMsg(MsgTypeReactrResult, bytes)
-			} else if resultString, isString := result.(string); isString {
-				// if the job returned a string
-				replyMsg = grav.NewMsg(MsgTypeReactrResult, []byte(resultString))
-			} else {
-				// if the job returned something else like a struct
-				resultJSON, err := json.Marshal(result)
-				if err != nil {
-					h.log.Error(errors.Wrapf(err, "job from message %s returned result that could not be JSON marshalled", msg.UUID()))
-					replyMsg = grav.NewMsg(MsgTypeReactrJobErr, []byte(errors.Wrap(err, "failed to Marshal job result").Error()))
-				}
+		job := NewJob(msgType, msg.Data())
+
+		metaMsg, isMetaMsg := msg.(MetadataMessage)
+		if isMetaMsg {
+			for k, v := range metaMsg.Metadata() {
+				job = job.WithMeta(k, v)
+			}
+		}
+
+		result, err := h.Do(job).Then()
+
+		replyMsg := h.resultToMsg(msg, job.UUID(), result, err, opts)
 
-				replyMsg = grav.NewMsg(MsgTypeReactrResult, resultJSON)
+		// replies to messages with metadata carry the job's metadata, so wrap them if they can't already
+		if _, isMetaReply := replyMsg.(MetadataMessage); isMetaMsg && !isMetaReply {
+			replyMsg = WithMetadata(replyMsg, nil)
+		}
+
+		if metaReply, isMetaReply := replyMsg.(MetadataMessage); isMetaReply {
+			existing := metaReply.Metadata()
+
+			for k, v := range job.meta {
+				if _, exists := existing[k]; !exists {
+					metaReply.SetMetadata(k, v)
+				}
 			}
 		}
 
@@ -119,6 +158,62 @@ func (h *Reactr) Listen(pod *grav.Pod, msgType string) {
 	})
 }
 
+// resultToMsg converts the result of a job triggered by msg into a reply message
+func (h *Reactr) resultToMsg(msg grav.Message, jobUUID string, result interface{}, err error, opts listenOpts) grav.Message {
+	if err != nil {
+		h.log.Error(errors.Wrapf(err, "job from message %s returned error result", msg.UUID()))
+		return jobErrMsg(newJobError(jobUUID, err))
+	}
+
+	if result == nil {
+		// if the job returned no result
+		return grav.NewMsg(MsgTypeReactrNilResult, []byte{})
+	}
+
+	if resultMsg, isMsg := result.(grav.Message); isMsg {
+		// if the job returned a Grav message
+		return resultMsg
+	}
+
+	// if the job returned something else like bytes or a struct
+	replyMsg, err := opts.encoder(opts.resultMsgType, result)
+	if err != nil {
+		h.log.Error(errors.Wrapf(err, "job from message %s returned result that could not be encoded", msg.UUID()))
+		return jobErrMsg(&JobError{Code: ErrorCodeEncodeFailed, Message: err.Error(), JobUUID: jobUUID})
+	}
+
+	return replyMsg
+}
+
+func jobErrMsg(jobErr *JobError) grav.Message {
+	// JobError only contains strings and ints, so Marshal cannot fail
+	errJSON, _ := json.Marshal(jobErr)
+
+	return grav.NewMsg(MsgTypeReactrJobErr, errJSON)
+}
+
+// Federate connects the Reactr instance to its peers using the provided Grav pod, which should not be used for anything else.
+// The Reactr will advertise its registered job types and load to its peers, and any job passed to Do that does not have a local
+// handler (or whose handler is saturated, see OverflowToPeers) will be forwarded to the least loaded peer that can handle it.
+// Job data and results are converted to bytes to be sent to peers, so results from forwarded jobs are always []byte or nil.
+// Calling Federate again replaces the previous federation, as if Unfederate had been called first.
+func (h *Reactr) Federate(pod *grav.Pod) {
+	router := newPeerRouter(pod, h.scheduler, h.log)
+
+	if old := h.scheduler.useRouter(router); old != nil {
+		old.stop()
+	}
+
+	router.start()
+}
+
+// Unfederate stops advertising to and forwarding jobs to peers. Jobs that are still waiting for a peer fail with ErrFederationStopped.
+func (h *Reactr) Unfederate() {
+	if old := h.scheduler.useRouter(nil); old != nil {
+		old.stop()
+	}
+}
+
 // Job is a shorter alias for NewJob
 func (h *Reactr) Job(jobType string, data interface{}) Job {
 	return NewJob(jobType, data)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/suborbital/grav/testutil"
@@ -158,3 +159,133 @@ func TestPreWarmWorker(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+type versionRunner struct {
+	version string
+	delay   time.Duration
+	stopped chan bool
+}
+
+func (v *versionRunner) Run(job Job, ctx *Ctx) (interface{}, error) {
+	time.Sleep(v.delay)
+
+	return v.version, nil
+}
+
+func (v *versionRunner) OnChange(change ChangeEvent) error {
+	if change == ChangeTypeStop {
+		v.stopped <- true
+	}
+
+	return nil
+}
+
+func TestUnhandle(t *testing.T) {
+	h := New()
+
+	runner := &versionRunner{version: "v1", delay: time.Millisecond * 200, stopped: make(chan bool, 1)}
+	doVersion := h.Handle("version", runner)
+
+	res := doVersion(nil)
+
+	// give the job a chance to be scheduled before the handler is removed
+	time.Sleep(time.Millisecond * 50)
+
+	if err := h.Unhandle("version"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the in-flight job should be allowed to complete
+	if val, err := res.Then(); err != nil {
+		t.Error(err)
+	} else if val.(string) != "v1" {
+		t.Errorf("expected v1, got %s", val.(string))
+	}
+
+	select {
+	case <-runner.stopped:
+	default:
+		t.Error("expected Runnable to be stopped")
+	}
+
+	if _, err := doVersion(nil).Then(); err == nil {
+		t.Error("expected error after Unhandle, did not get one")
+	}
+
+	if err := h.Unhandle("version"); err == nil {
+		t.Error("expected error unhandling twice, did not get one")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	h := New()
+
+	oldRunner := &versionRunner{version: "v1", delay: time.Millisecond * 200, stopped: make(chan bool, 1)}
+	doVersion := h.Handle("version", oldRunner)
+
+	oldRes := doVersion(nil)
+
+	time.Sleep(time.Millisecond * 50)
+
+	newRunner := &versionRunner{version: "v2", stopped: make(chan bool, 1)}
+	if err := h.Replace("version", newRunner); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, err := doVersion(nil).Then(); err != nil {
+		t.Error(err)
+	} else if val.(string) != "v2" {
+		t.Errorf("expected v2, got %s", val.(string))
+	}
+
+	if val, err := oldRes.Then(); err != nil {
+		t.Error(err)
+	} else if val.(string) != "v1" {
+		t.Errorf("expected v1, got %s", val.(string))
+	}
+
+	select {
+	case <-oldRunner.stopped:
+	case <-time.After(time.Second):
+		t.Error("expected old Runnable to be stopped")
+	}
+
+	if err := h.Replace("nope", newRunner); err == nil {
+		t.Error("expected error replacing missing handler, did not get one")
+	}
+}
+
+func TestOnStopped(t *testing.T) {
+	h := New()
+
+	stopped := make(chan bool, 1)
+
+	runner := &versionRunner{version: "v1", delay: time.Millisecond * 200, stopped: make(chan bool, 1)}
+	doVersion := h.Handle("version", runner, OnStopped(func() { stopped <- true }))
+
+	res := doVersion(nil)
+
+	time.Sleep(time.Millisecond * 50)
+
+	if err := h.Replace("version", &versionRunner{version: "v2", stopped: make(chan bool, 1)}); err != nil {
+		t.Error(errors.Wrap(err, "failed to Replace"))
+		return
+	}
+
+	// the old handler is only stopped once its in-flight job completes
+	select {
+	case <-stopped:
+		t.Error("expected OnStopped not to be called before the in-flight job completed")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if _, err := res.Then(); err != nil {
+		t.Error(errors.Wrap(err, "failed to Then"))
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("expected OnStopped to be called")
+	}
+}
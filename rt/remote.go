@@ -0,0 +1,163 @@
+package rt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/grav/grav"
+)
+
+// ErrRemoteTimeout and others are returned when a reply to RemoteDo is not received
+var (
+	ErrRemoteTimeout  = errors.New("timed out waiting for remote result")
+	ErrRemoteReleased = errors.New("pod was released before the remote result was received")
+)
+
+const defaultRemoteTimeout = time.Second * 30
+
+// remoteClients maps each pod used with RemoteDo to the client that correlates its replies
+type remoteClients struct {
+	clients map[*grav.Pod]*remoteClient
+	lock    sync.Mutex
+}
+
+// remoteClient matches replies received by a pod with the Results waiting for them
+type remoteClient struct {
+	pending map[string]*Result
+	lock    sync.Mutex
+}
+
+func newRemoteClients() *remoteClients {
+	r := &remoteClients{
+		clients: map[*grav.Pod]*remoteClient{},
+		lock:    sync.Mutex{},
+	}
+
+	return r
+}
+
+// RemoteDo sends a message of type jobType over the provided Grav pod to trigger a job on a Reactr instance that
+// is Listening for it, and returns a Result that will contain the job's result once the reply is received.
+// reactr.result replies become []byte results, reactr.nil replies become nil results, reactr.joberr replies become *JobError errors,
+// and any other reply (i.e. when the job returned a grav.Message) is returned as the grav.Message itself.
+// The reply is waited for for 30 seconds. The pod must be dedicated to RemoteDo, since the Reactr replaces its OnFunc
+// to receive replies, until it is released using ReleaseRemote.
+func (h *Reactr) RemoteDo(pod *grav.Pod, jobType string, data interface{}) *Result {
+	return h.RemoteDoWithTimeout(pod, jobType, data, defaultRemoteTimeout)
+}
+
+// RemoteDoWithTimeout is RemoteDo with a custom timeout. If timeout is 0 or less, the reply is waited for forever.
+func (h *Reactr) RemoteDoWithTimeout(pod *grav.Pod, jobType string, data interface{}, timeout time.Duration) *Result {
+	client := h.remotes.clientForPod(pod)
+
+	dataBytes, err := toBytes(data)
+	if err != nil {
+		result := newResult("", func(_ string) {})
+		result.sendErr(errors.Wrap(err, "failed to convert job data to bytes"))
+		return result
+	}
+
+	msg := grav.NewMsg(jobType, dataBytes)
+	result := newResult(msg.UUID(), func(_ string) {})
+
+	client.lock.Lock()
+	client.pending[msg.UUID()] = result
+	client.lock.Unlock()
+
+	if pod.Send(msg) == nil {
+		client.take(msg.UUID())
+		result.sendErr(errors.New("failed to Send, pod is disconnected"))
+		return result
+	}
+
+	if timeout > 0 {
+		go func() {
+			<-h.scheduler.opts.clock.After(timeout)
+
+			// if the result is still pending, nothing else will deliver it
+			if res := client.take(msg.UUID()); res != nil {
+				res.sendErr(ErrRemoteTimeout)
+			}
+		}()
+	}
+
+	return result
+}
+
+// ReleaseRemote releases the state kept for a pod used with RemoteDo, failing any results still waiting
+// for a reply with ErrRemoteReleased. Replies received by the pod afterwards are ignored.
+func (h *Reactr) ReleaseRemote(pod *grav.Pod) {
+	h.remotes.lock.Lock()
+	client, exists := h.remotes.clients[pod]
+	delete(h.remotes.clients, pod)
+	h.remotes.lock.Unlock()
+
+	if !exists {
+		return
+	}
+
+	client.lock.Lock()
+	pending := client.pending
+	client.pending = map[string]*Result{}
+	client.lock.Unlock()
+
+	for _, result := range pending {
+		result.sendErr(ErrRemoteReleased)
+	}
+}
+
+func (r *remoteClients) clientForPod(pod *grav.Pod) *remoteClient {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if client, exists := r.clients[pod]; exists {
+		return client
+	}
+
+	client := &remoteClient{
+		pending: map[string]*Result{},
+		lock:    sync.Mutex{},
+	}
+
+	pod.On(client.onMsg)
+
+	r.clients[pod] = client
+
+	return client
+}
+
+func (r *remoteClient) onMsg(msg grav.Message) error {
+	result := r.take(msg.ReplyTo())
+	if result == nil {
+		return nil
+	}
+
+	switch msg.Type() {
+	case MsgTypeReactrResult:
+		result.sendResult(msg.Data())
+	case MsgTypeReactrNilResult:
+		result.sendResult(nil)
+	case MsgTypeReactrJobErr:
+		result.sendErr(jobErrorFromMsg(msg))
+	default:
+		result.sendResult(msg)
+	}
+
+	return nil
+}
+
+// take removes and returns the pending result for the message UUID, if any
+func (r *remoteClient) take(msgUUID string) *Result {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	result, exists := r.pending[msgUUID]
+	if !exists {
+		return nil
+	}
+
+	delete(r.pending, msgUUID)
+
+	return result
+}
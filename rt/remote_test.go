@@ -0,0 +1,94 @@
+package rt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/grav/grav"
+)
+
+func TestRemoteDo(t *testing.T) {
+	r := New()
+	g := grav.New()
+
+	r.HandleMsg(g.Connect(), "remote.generic", generic{})
+	r.HandleMsg(g.Connect(), msgTypeNil, &nilRunner{})
+	r.HandleMsg(g.Connect(), msgTypeTester, &msgRunner{})
+
+	pod := g.Connect()
+
+	res, err := r.RemoteDo(pod, "remote.generic", "hello").Then()
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to RemoteDo"))
+		return
+	}
+
+	if string(res.([]byte)) != "hello" {
+		t.Error("expected 'hello', got", res)
+	}
+
+	if _, err := r.RemoteDo(pod, "remote.generic", "fail").Then(); err == nil || err.Error() != "error" {
+		t.Error("expected remote error, got", err)
+	}
+
+	if res, err := r.RemoteDo(pod, msgTypeNil, "hi").Then(); err != nil || res != nil {
+		t.Error("expected nil result, got", res, err)
+	}
+
+	res, err = r.RemoteDo(pod, msgTypeTester, "charlie brown").Then()
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to RemoteDo"))
+		return
+	}
+
+	if msg, isMsg := res.(grav.Message); !isMsg || string(msg.Data()) != "hello, charlie brown" {
+		t.Error("expected message result, got", res)
+	}
+}
+
+func TestRemoteDoGroup(t *testing.T) {
+	r := New()
+	g := grav.New()
+
+	r.HandleMsg(g.Connect(), "remote.math", generic{}, PoolSize(3))
+
+	pod := g.Connect()
+
+	grp := NewGroup()
+	for i := 0; i < 100; i++ {
+		grp.Add(r.RemoteDo(pod, "remote.math", "hi"))
+	}
+
+	if err := grp.Wait(); err != nil {
+		t.Error(errors.Wrap(err, "failed to grp.Wait"))
+	}
+}
+
+func TestRemoteDoTimeout(t *testing.T) {
+	r := New()
+	g := grav.New()
+
+	if _, err := r.RemoteDoWithTimeout(g.Connect(), "nobody.listening", "hi", time.Millisecond*100).Then(); err != ErrRemoteTimeout {
+		t.Error("expected ErrRemoteTimeout, got", err)
+	}
+}
+
+func TestReleaseRemote(t *testing.T) {
+	r := New()
+	g := grav.New()
+
+	pod := g.Connect()
+
+	res := r.RemoteDoWithTimeout(pod, "nobody.listening", "hi", 0)
+
+	r.ReleaseRemote(pod)
+
+	if _, err := res.Then(); err != ErrRemoteReleased {
+		t.Error("expected ErrRemoteReleased, got", err)
+	}
+
+	if len(r.remotes.clients) != 0 {
+		t.Error("expected the pod's state to be released")
+	}
+}
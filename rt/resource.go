@@ -0,0 +1,91 @@
+package rt
+
+import (
+	"sync"
+	"time"
+)
+
+// resourcePool bounds the number of jobs that can run concurrently across every handler bound to it
+type resourcePool struct {
+	name  string
+	size  int
+	inUse int
+
+	lock sync.Mutex
+	cond *sync.Cond
+}
+
+func newResourcePool(name string, size int) *resourcePool {
+	r := &resourcePool{
+		name: name,
+		size: size,
+		lock: sync.Mutex{},
+	}
+
+	r.cond = sync.NewCond(&r.lock)
+
+	return r
+}
+
+// acquire waits for a slot to become available and takes it, returning false without taking a slot if cancel
+// receives first. A nil cancel channel waits indefinitely.
+func (r *resourcePool) acquire(cancel <-chan time.Time) bool {
+	cancelled := false
+	done := make(chan struct{})
+	defer close(done)
+
+	if cancel != nil {
+		go func() {
+			select {
+			case <-cancel:
+				r.lock.Lock()
+				cancelled = true
+				r.cond.Broadcast()
+				r.lock.Unlock()
+			case <-done:
+			}
+		}()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for r.inUse >= r.size {
+		if cancelled {
+			return false
+		}
+
+		r.cond.Wait()
+	}
+
+	r.inUse++
+
+	return true
+}
+
+// release returns a slot to the pool
+func (r *resourcePool) release() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.inUse--
+	r.cond.Signal()
+}
+
+// setSize changes the number of slots in the pool. Jobs already running are not affected.
+func (r *resourcePool) setSize(size int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.size = size
+	r.cond.Broadcast()
+}
+
+// holding wraps a RunFunc such that the slot it has already acquired is released once it returns
+func (r *resourcePool) holding(run RunFunc) RunFunc {
+	return func(job Job, ctx *Ctx) (interface{}, error) {
+		defer r.release()
+
+		return run(job, ctx)
+	}
+}
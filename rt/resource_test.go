@@ -0,0 +1,120 @@
+package rt
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type concurrencyRunner struct {
+	current int
+	max     int
+	lock    *sync.Mutex
+}
+
+func (c *concurrencyRunner) Run(job Job, ctx *Ctx) (interface{}, error) {
+	c.lock.Lock()
+	c.current++
+	if c.current > c.max {
+		c.max = c.current
+	}
+	c.lock.Unlock()
+
+	time.Sleep(time.Millisecond * 20)
+
+	c.lock.Lock()
+	c.current--
+	c.lock.Unlock()
+
+	return nil, nil
+}
+
+func (c *concurrencyRunner) OnChange(_ ChangeEvent) error { return nil }
+
+func TestResourcePool(t *testing.T) {
+	r := New()
+
+	// both handlers share the same runner so that their combined concurrency is measured
+	runner := &concurrencyRunner{lock: &sync.Mutex{}}
+
+	doUsers := r.Handle("users", runner, PoolSize(5), ResourcePool("db", 3))
+	doOrders := r.Handle("orders", runner, PoolSize(5), ResourcePool("db", 3))
+
+	grp := NewGroup()
+	for i := 0; i < 10; i++ {
+		grp.Add(doUsers(nil))
+		grp.Add(doOrders(nil))
+	}
+
+	if err := grp.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if runner.max > 3 {
+		t.Errorf("expected at most 3 concurrent jobs, got %d", runner.max)
+	}
+
+	if runner.max < 2 {
+		t.Errorf("expected jobs to run concurrently, max was %d", runner.max)
+	}
+
+	for _, info := range r.Handlers() {
+		if info.ResourcePool != "db" {
+			t.Errorf("expected %s to be bound to db, got %q", info.JobType, info.ResourcePool)
+		}
+	}
+}
+
+type countRunner struct {
+	count int32
+	sleep time.Duration
+}
+
+func (c *countRunner) Run(job Job, ctx *Ctx) (interface{}, error) {
+	atomic.AddInt32(&c.count, 1)
+
+	time.Sleep(c.sleep)
+
+	return nil, nil
+}
+
+func (c *countRunner) OnChange(_ ChangeEvent) error { return nil }
+
+func TestResourcePoolTimeout(t *testing.T) {
+	r := New()
+
+	slow := &countRunner{sleep: time.Millisecond * 200}
+	fast := &countRunner{}
+
+	doSlow := r.Handle("slow", slow, ResourcePool("db", 1))
+	doFast := r.Handle("fast", fast, ResourcePool("db", 1), Timeout(time.Millisecond*30))
+
+	slowResult := doSlow(nil)
+
+	// give the slow job time to take the only slot
+	time.Sleep(time.Millisecond * 20)
+
+	if _, err := doFast(nil).Then(); err != ErrJobTimeout {
+		t.Error("expected ErrJobTimeout, got", err)
+		return
+	}
+
+	if _, err := slowResult.Then(); err != nil {
+		t.Error(errors.Wrap(err, "slow job failed"))
+		return
+	}
+
+	time.Sleep(time.Millisecond * 20)
+
+	if count := atomic.LoadInt32(&fast.count); count != 0 {
+		t.Errorf("expected the timed out job never to run, ran %d times", count)
+	}
+
+	// the slot must have been released for later jobs
+	if _, err := doFast(nil).Then(); err != nil {
+		t.Error(errors.Wrap(err, "fast job failed"))
+	}
+}
@@ -0,0 +1,89 @@
+package rttest
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is an rt.Clock whose time only moves when Advance is called
+type FakeClock struct {
+	now     time.Time
+	waiters []waiter
+	lock    sync.Mutex
+	cond    *sync.Cond
+}
+
+type waiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+// NewFakeClock creates a FakeClock set to the provided time
+func NewFakeClock(now time.Time) *FakeClock {
+	f := &FakeClock{
+		now:     now,
+		waiters: []waiter{},
+	}
+
+	f.cond = sync.NewCond(&f.lock)
+
+	return f
+}
+
+// Now returns the clock's current time
+func (f *FakeClock) Now() time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.now
+}
+
+// After returns a channel that receives the clock's time once it has been advanced by at least d
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	ch := make(chan time.Time, 1)
+
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+
+	f.waiters = append(f.waiters, waiter{until: f.now.Add(d), ch: ch})
+	f.cond.Broadcast()
+
+	return ch
+}
+
+// Advance moves the clock forward by d, firing any timers that have become due
+func (f *FakeClock) Advance(d time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.now = f.now.Add(d)
+
+	remaining := []waiter{}
+	for _, w := range f.waiters {
+		if f.now.Before(w.until) {
+			remaining = append(remaining, w)
+			continue
+		}
+
+		w.ch <- f.now
+	}
+
+	f.waiters = remaining
+}
+
+// BlockUntil waits until at least n timers are waiting on the clock. It is used to ensure that
+// the goroutines using the clock (such as a Reactr's schedule watcher, or a job with a timeout)
+// have caught up before calling Advance.
+func (f *FakeClock) BlockUntil(n int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
@@ -0,0 +1,92 @@
+// Package rttest provides helpers for deterministically testing Runnables and Schedules
+package rttest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/suborbital/reactr/rt"
+)
+
+// New creates a Reactr that runs jobs synchronously (see rt.Synchronous) and uses the provided clock,
+// such that schedules and job timeouts only progress when the clock is advanced
+func New(clock *FakeClock) *rt.Reactr {
+	return rt.New(rt.Synchronous(), rt.UseClock(clock))
+}
+
+// Recorder records the jobs scheduled by a Runnable using Ctx.Do
+type Recorder struct {
+	reactr *rt.Reactr
+	cache  rt.Cache
+	jobs   []rt.Job
+	lock   sync.Mutex
+}
+
+// NewRecorder creates a Recorder. Recorded jobs are passed to reactr to be run,
+// so that a Runnable's dependencies can be handled by fakes, or by the real thing.
+// If reactr is nil, a synchronous Reactr with no handlers is used, so recorded jobs will return errors.
+func NewRecorder(reactr *rt.Reactr) *Recorder {
+	if reactr == nil {
+		reactr = New(NewFakeClock(time.Now()))
+	}
+
+	r := &Recorder{
+		reactr: reactr,
+		cache:  rt.NewMemoryCache(),
+		jobs:   []rt.Job{},
+		lock:   sync.Mutex{},
+	}
+
+	return r
+}
+
+// Ctx returns a job context that records the jobs passed to its Do function. Every context
+// from the Recorder shares the same in-memory cache, so values set by one job can be read by the next.
+func (r *Recorder) Ctx() *rt.Ctx {
+	return rt.NewCtx(r.cache, r.do)
+}
+
+// Run runs a Runnable with a job, using a job context from the Recorder
+func (r *Recorder) Run(runnable rt.Runnable, job rt.Job) (interface{}, error) {
+	return runnable.Run(job, r.Ctx())
+}
+
+// Jobs returns the jobs that have been recorded, in the order they were scheduled
+func (r *Recorder) Jobs() []rt.Job {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	jobs := make([]rt.Job, len(r.jobs))
+	copy(jobs, r.jobs)
+
+	return jobs
+}
+
+// JobsOfType returns the recorded jobs with the given job type
+func (r *Recorder) JobsOfType(jobType string) []rt.Job {
+	jobs := []rt.Job{}
+
+	for _, j := range r.Jobs() {
+		if j.Type() == jobType {
+			jobs = append(jobs, j)
+		}
+	}
+
+	return jobs
+}
+
+// Reset clears the recorded jobs
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.jobs = []rt.Job{}
+}
+
+func (r *Recorder) do(job rt.Job) *rt.Result {
+	r.lock.Lock()
+	r.jobs = append(r.jobs, job)
+	r.lock.Unlock()
+
+	return r.reactr.Do(job)
+}
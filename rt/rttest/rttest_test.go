@@ -0,0 +1,237 @@
+package rttest
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/reactr/rt"
+)
+
+type counter struct {
+	count int64
+}
+
+func (c *counter) Run(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
+	return atomic.AddInt64(&c.count, 1), nil
+}
+
+func (c *counter) OnChange(_ rt.ChangeEvent) error { return nil }
+
+func (c *counter) get() int64 {
+	return atomic.LoadInt64(&c.count)
+}
+
+func TestSynchronous(t *testing.T) {
+	r := New(NewFakeClock(time.Now()))
+
+	c := &counter{}
+	doCount := r.Handle("count", c)
+
+	res := doCount(nil)
+
+	// the job should have already run by the time Do returns
+	if c.get() != 1 {
+		t.Errorf("expected job to have run synchronously, count is %d", c.get())
+	}
+
+	if val, err := res.Then(); err != nil {
+		t.Error(err)
+	} else if val.(int64) != 1 {
+		t.Errorf("expected 1, got %d", val.(int64))
+	}
+}
+
+func TestEvery(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	r := New(clock)
+
+	c := &counter{}
+	r.Handle("count", c)
+
+	r.Schedule(rt.Every(5, func() rt.Job {
+		return rt.NewJob("count", nil)
+	}))
+
+	// wait for the watcher's first pass, which runs the job immediately
+	clock.BlockUntil(1)
+
+	for i := 1; i <= 10; i++ {
+		clock.Advance(time.Second)
+		clock.BlockUntil(1)
+	}
+
+	if c.get() != 3 {
+		t.Errorf("expected 3 jobs, got %d", c.get())
+	}
+}
+
+func TestEveryDuration(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	r := New(clock)
+
+	c := &counter{}
+	r.Handle("count", c)
+
+	r.Schedule(rt.EveryDuration(time.Millisecond*250, func() rt.Job {
+		return rt.NewJob("count", nil)
+	}))
+
+	clock.BlockUntil(1)
+
+	// the watcher should wake up in time for each interval rather than once per second
+	for i := 1; i <= 4; i++ {
+		clock.Advance(time.Millisecond * 250)
+		clock.BlockUntil(1)
+	}
+
+	if c.get() != 5 {
+		t.Errorf("expected 5 jobs, got %d", c.get())
+	}
+}
+
+func TestAfter(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	r := New(clock)
+
+	c := &counter{}
+	r.Handle("count", c)
+
+	r.Schedule(rt.After(3, func() rt.Job {
+		return rt.NewJob("count", nil)
+	}))
+
+	clock.BlockUntil(1)
+
+	for i := 1; i <= 2; i++ {
+		clock.Advance(time.Second)
+		clock.BlockUntil(1)
+	}
+
+	if c.get() != 0 {
+		t.Errorf("expected 0 jobs before 3 seconds, got %d", c.get())
+	}
+
+	for i := 1; i <= 5; i++ {
+		clock.Advance(time.Second)
+		clock.BlockUntil(1)
+	}
+
+	if c.get() != 1 {
+		t.Errorf("expected 1 job, got %d", c.get())
+	}
+}
+
+type blocker struct {
+	unblock chan bool
+}
+
+func (b *blocker) Run(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
+	<-b.unblock
+	return nil, nil
+}
+
+func (b *blocker) OnChange(_ rt.ChangeEvent) error { return nil }
+
+func TestTimeout(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	r := rt.New(rt.UseClock(clock))
+
+	b := &blocker{unblock: make(chan bool)}
+	defer close(b.unblock)
+
+	doBlock := r.Handle("block", b, rt.TimeoutSeconds(30))
+
+	res := doBlock(nil)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second * 30)
+
+	if _, err := res.Then(); err != rt.ErrJobTimeout {
+		t.Errorf("expected ErrJobTimeout, got %v", err)
+	}
+}
+
+type fanOut struct{}
+
+func (f fanOut) Run(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
+	total := 0
+
+	for _, s := range []string{"a", "bb", "ccc"} {
+		length, err := ctx.Do(rt.NewJob("length", s)).ThenInt()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to Do length")
+		}
+
+		total += length
+	}
+
+	return total, nil
+}
+
+func (f fanOut) OnChange(_ rt.ChangeEvent) error { return nil }
+
+func TestRecorder(t *testing.T) {
+	r := New(NewFakeClock(time.Now()))
+	r.Handle("length", rt.RunnableFunc(func(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
+		return len(job.String()), nil
+	}))
+
+	rec := NewRecorder(r)
+
+	res, err := rec.Run(fanOut{}, rt.NewJob("fanout", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.(int) != 6 {
+		t.Errorf("expected 6, got %d", res.(int))
+	}
+
+	jobs := rec.JobsOfType("length")
+	if len(jobs) != 3 {
+		t.Fatalf("expected 3 recorded jobs, got %d", len(jobs))
+	}
+
+	if jobs[2].String() != "ccc" {
+		t.Errorf("expected third job to be 'ccc', got %q", jobs[2].String())
+	}
+
+	rec.Reset()
+
+	if len(rec.Jobs()) != 0 {
+		t.Error("expected no jobs after Reset")
+	}
+}
+
+func TestRecorderWithoutReactr(t *testing.T) {
+	rec := NewRecorder(nil)
+
+	if _, err := rec.Run(fanOut{}, rt.NewJob("fanout", nil)); err == nil {
+		t.Error("expected error for unhandled job, did not get one")
+	}
+
+	if len(rec.Jobs()) != 1 {
+		t.Errorf("expected 1 recorded job, got %d", len(rec.Jobs()))
+	}
+}
+
+func TestRecorderCache(t *testing.T) {
+	rec := NewRecorder(nil)
+
+	if err := rec.Ctx().Cache.Set("key", []byte("val"), 0); err != nil {
+		t.Error(errors.Wrap(err, "failed to Set"))
+		return
+	}
+
+	val, err := rec.Ctx().Cache.Get("key")
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to Get"))
+		return
+	}
+
+	if string(val) != "val" {
+		t.Error("expected 'val', got", string(val))
+	}
+}
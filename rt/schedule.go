@@ -1,6 +1,11 @@
 package rt
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 /**
 This is not to be confused with the `scheduler` type, which is internal to the Reactr instance and actually schedules
@@ -15,27 +20,96 @@ type Schedule interface {
 	Done() bool
 }
 
+// ScheduleKindEvery and others describe the kind of a watched Schedule
+const (
+	ScheduleKindEvery  = "every"
+	ScheduleKindAfter  = "after"
+	ScheduleKindCustom = "custom"
+)
+
+// ScheduleInfo is a snapshot of a Schedule being watched by a Reactr instance. Interval is the interval of an
+// Every schedule or the delay of an After schedule, and NextRun is zero for custom schedules.
+type ScheduleInfo struct {
+	ID       string        `json:"id"`
+	Kind     string        `json:"kind"`
+	Interval time.Duration `json:"interval"`
+	NextRun  time.Time     `json:"nextRun"`
+}
+
+// MarshalJSON encodes the Interval as a duration string such as "1m0s", rather than as nanoseconds
+func (s ScheduleInfo) MarshalJSON() ([]byte, error) {
+	// the alias type doesn't have this method, so marshalling it doesn't recurse
+	type scheduleInfo ScheduleInfo
+
+	info := struct {
+		scheduleInfo
+		Interval string `json:"interval"`
+	}{scheduleInfo(s), s.Interval.String()}
+
+	return json.Marshal(info)
+}
+
+// UnmarshalJSON decodes a ScheduleInfo encoded by MarshalJSON
+func (s *ScheduleInfo) UnmarshalJSON(data []byte) error {
+	type scheduleInfo ScheduleInfo
+
+	info := struct {
+		*scheduleInfo
+		Interval string `json:"interval"`
+	}{scheduleInfo: (*scheduleInfo)(s)}
+
+	if err := json.Unmarshal(data, &info); err != nil {
+		return err
+	}
+
+	interval, err := time.ParseDuration(info.Interval)
+	if err != nil {
+		return errors.Wrap(err, "failed to ParseDuration interval")
+	}
+
+	s.Interval = interval
+
+	return nil
+}
+
+// clockedSchedule is a Schedule that uses the Clock of the Reactr watching it
+type clockedSchedule interface {
+	useClock(Clock)
+}
+
+// timedSchedule is a Schedule that knows when it will next have a job, allowing the watcher to wake up in time for it
+type timedSchedule interface {
+	nextCheck() time.Time
+}
+
 type everySchedule struct {
-	jobFunc func() Job
-	seconds int
-	last    *time.Time
+	jobFunc  func() Job
+	interval time.Duration
+	last     *time.Time
+	clock    Clock
 }
 
 // Every returns a Schedule that will schedule the job provided by jobFunc every x seconds
 func Every(seconds int, jobFunc func() Job) Schedule {
+	return EveryDuration(time.Second*time.Duration(seconds), jobFunc)
+}
+
+// EveryDuration returns a Schedule that will schedule the job provided by jobFunc at the given interval, with millisecond resolution
+func EveryDuration(interval time.Duration, jobFunc func() Job) Schedule {
 	e := &everySchedule{
-		jobFunc: jobFunc,
-		seconds: seconds,
+		jobFunc:  jobFunc,
+		interval: interval,
+		clock:    systemClock{},
 	}
 
 	return e
 }
 
 func (e *everySchedule) Check() *Job {
-	now := time.Now()
+	now := e.clock.Now()
 
-	// return a job if this schedule has never been checked OR the 'last' job was more than x seconds ago
-	if e.last == nil || time.Since(*e.last).Seconds() >= float64(e.seconds) {
+	// return a job if this schedule has never been checked OR the 'last' job was at least one interval ago
+	if e.last == nil || now.Sub(*e.last) >= e.interval {
 		e.last = &now
 
 		job := e.jobFunc()
@@ -49,27 +123,46 @@ func (e *everySchedule) Done() bool {
 	return false
 }
 
+func (e *everySchedule) useClock(clock Clock) {
+	e.clock = clock
+}
+
+func (e *everySchedule) nextCheck() time.Time {
+	if e.last == nil {
+		return e.clock.Now()
+	}
+
+	return e.last.Add(e.interval)
+}
+
 type afterSchedule struct {
 	jobFunc func() Job
-	seconds int
+	delay   time.Duration
 	created time.Time
 	done    bool
+	clock   Clock
 }
 
 // After returns a schedule that will schedule the job provided by jobFunc one time x seconds after creation
 func After(seconds int, jobFunc func() Job) Schedule {
+	return AfterDuration(time.Second*time.Duration(seconds), jobFunc)
+}
+
+// AfterDuration returns a schedule that will schedule the job provided by jobFunc one time after the given delay, with millisecond resolution
+func AfterDuration(delay time.Duration, jobFunc func() Job) Schedule {
 	a := &afterSchedule{
 		jobFunc: jobFunc,
-		seconds: seconds,
+		delay:   delay,
 		created: time.Now(),
 		done:    false,
+		clock:   systemClock{},
 	}
 
 	return a
 }
 
 func (a *afterSchedule) Check() *Job {
-	if time.Since(a.created).Seconds() >= float64(a.seconds) {
+	if a.clock.Now().Sub(a.created) >= a.delay {
 		a.done = true
 		job := a.jobFunc()
 
@@ -82,3 +175,17 @@ func (a *afterSchedule) Check() *Job {
 func (a *afterSchedule) Done() bool {
 	return a.done
 }
+
+func (a *afterSchedule) nextCheck() time.Time {
+	return a.created.Add(a.delay)
+}
+
+// useClock switches the schedule to another Clock, measuring from the time it was watched according to that Clock
+func (a *afterSchedule) useClock(clock Clock) {
+	if clock == a.clock {
+		return
+	}
+
+	a.clock = clock
+	a.created = clock.Now()
+}
@@ -2,6 +2,7 @@ package rt
 
 import (
 	"testing"
+	"time"
 
 	"github.com/suborbital/grav/testutil"
 )
@@ -33,3 +34,46 @@ func TestScheduleAfter(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+type customSchedule struct{}
+
+func (c customSchedule) Check() *Job { return nil }
+
+func (c customSchedule) Done() bool { return false }
+
+func TestSchedules(t *testing.T) {
+	r := New()
+
+	r.Handle("counter", &counterRunner{testutil.NewAsyncCounter(10)})
+
+	r.Schedule(EveryDuration(time.Hour, func() Job {
+		return NewJob("counter", nil)
+	}))
+
+	r.Schedule(AfterDuration(time.Hour, func() Job {
+		return NewJob("counter", nil)
+	}))
+
+	r.Schedule(customSchedule{})
+
+	kinds := map[string]ScheduleInfo{}
+	for _, s := range r.Schedules() {
+		kinds[s.Kind] = s
+	}
+
+	if len(kinds) != 3 {
+		t.Fatalf("expected 3 kinds of schedule, got %d", len(kinds))
+	}
+
+	if every := kinds[ScheduleKindEvery]; every.Interval != time.Hour || every.NextRun.IsZero() {
+		t.Errorf("unexpected every schedule %+v", every)
+	}
+
+	if after := kinds[ScheduleKindAfter]; after.Interval != time.Hour || time.Until(after.NextRun) < time.Minute*59 {
+		t.Errorf("unexpected after schedule %+v", after)
+	}
+
+	if custom := kinds[ScheduleKindCustom]; !custom.NextRun.IsZero() {
+		t.Errorf("unexpected custom schedule %+v", custom)
+	}
+}
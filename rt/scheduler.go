@@ -2,6 +2,7 @@ package rt
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -9,87 +10,278 @@ import (
 )
 
 type scheduler struct {
-	workers map[string]*worker
-	watcher *watcher
-	store   Storage
-	cache   Cache
-	logger  *vlog.Logger
-	lock    sync.Mutex
+	workers    map[string]*worker
+	watcher    *watcher
+	store      Storage
+	cache      Cache
+	namespaces map[string]*cacheNamespace
+	resources  map[string]*resourcePool
+	router     *peerRouter
+	middleware []Middleware
+	opts       reactrOpts
+	logger     *vlog.Logger
+	lock       sync.Mutex
 }
 
-func newScheduler(logger *vlog.Logger, cache Cache) *scheduler {
+func newScheduler(logger *vlog.Logger, cache Cache, opts reactrOpts) *scheduler {
 	s := &scheduler{
-		workers: map[string]*worker{},
-		store:   newMemoryStorage(),
-		cache:   cache,
-		logger:  logger,
-		lock:    sync.Mutex{},
+		workers:    map[string]*worker{},
+		store:      newMemoryStorage(),
+		cache:      cache,
+		namespaces: map[string]*cacheNamespace{},
+		resources:  map[string]*resourcePool{},
+		middleware: []Middleware{},
+		opts:       opts,
+		logger:     logger,
+		lock:       sync.Mutex{},
 	}
 
-	s.watcher = newWatcher(s.schedule)
+	s.watcher = newWatcher(s.schedule, opts.clock)
 
 	return s
 }
 
 func (s *scheduler) schedule(job Job) *Result {
+	worker := s.getWorker(job.jobType)
+
+	// if there is no local worker for the job (or it is saturated), give a peer the chance to handle it
+	if router := s.getRouter(); router != nil && (worker == nil || worker.isSaturated()) {
+		if result := router.forward(job); result != nil {
+			return result
+		}
+	}
+
+	return s.scheduleLocal(job, worker)
+}
+
+// scheduleLocal schedules a job on the provided local worker, never forwarding it to a peer
+func (s *scheduler) scheduleLocal(job Job, worker *worker) *Result {
 	result := newResult(job.UUID(), func(uuid string) {
 		if err := s.store.Remove(uuid); err != nil {
 			s.logger.Error(errors.Wrapf(err, "scheduler failed to Remove Job %s from storage", uuid))
 		}
 	})
 
-	worker := s.getWorker(job.jobType)
 	if worker == nil {
 		result.sendErr(fmt.Errorf("failed to getWorker for jobType %q", job.jobType))
 		return result
 	}
 
-	go func() {
-		if !worker.isStarted() {
-			// "recursively" pass this function as the runFunc for the runnable
-			if err := worker.start(s.schedule); err != nil {
-				result.sendErr(errors.Wrapf(err, "failed start worker for jobType %q", job.jobType))
+	run := func() {
+		job.result = result
+		s.store.Add(job)
+
+		for {
+			if !worker.isStarted() {
+				// "recursively" pass this function as the runFunc for the runnable. A worker that was stopped
+				// before it could start will refuse the job below, so it can be handed to its replacement.
+				if err := worker.start(s.schedule); err != nil && err != ErrWorkerStopped {
+					result.sendErr(errors.Wrapf(err, "failed start worker for jobType %q", job.jobType))
+					return
+				}
+			}
+
+			if worker.schedule(job.Reference()) {
+				return
+			}
+
+			// the worker was replaced or removed after it was fetched, so hand the job to its replacement (if any)
+			worker = s.getWorker(job.jobType)
+			if worker == nil {
+				result.sendErr(errors.Wrapf(ErrWorkerStopped, "handler for jobType %q was removed", job.jobType))
 				return
 			}
 		}
+	}
 
+	if s.opts.synchronous {
+		run()
+	} else if worker.isPartitioned() && job.partitionKey != "" {
+		// jobs with a partition key must reach their partition in the order they were scheduled, so they are queued
+		// on the calling goroutine, while the worker (which may retry its OnStart for some time) starts in the background
 		job.result = result
 		s.store.Add(job)
 
-		worker.schedule(job.Reference())
-	}()
+		s.queuePartitioned(job.Reference(), worker)
+	} else {
+		go run()
+	}
 
 	return result
 }
 
-// handle adds a handler
+// queuePartitioned queues the job in its partition, and starts the worker in the background if needed
+func (s *scheduler) queuePartitioned(jobRef JobReference, worker *worker) {
+	for {
+		if worker == nil {
+			jobRef.result.sendErr(errors.Wrapf(ErrWorkerStopped, "handler for jobType %q was removed", jobRef.jobType))
+			return
+		}
+
+		if worker.schedule(jobRef) {
+			break
+		}
+
+		// the worker was replaced or removed after it was fetched, so hand the job to its replacement (if any)
+		worker = s.getWorker(jobRef.jobType)
+	}
+
+	if !worker.isStarted() {
+		go s.startPartitioned(worker, jobRef.jobType)
+	}
+}
+
+// startPartitioned starts a partitioned worker. If it fails to start, the jobs left waiting in its
+// partitions are failed, or handed to its replacement if it was stopped before it could start.
+func (s *scheduler) startPartitioned(worker *worker, jobType string) {
+	err := worker.start(s.schedule)
+	if err == nil {
+		return
+	}
+
+	for _, jobRef := range worker.unqueue() {
+		if err == ErrWorkerStopped {
+			s.queuePartitioned(jobRef, s.getWorker(jobType))
+		} else {
+			jobRef.result.sendErr(errors.Wrapf(err, "failed start worker for jobType %q", jobType))
+		}
+	}
+}
+
+// handle adds a handler, replacing (and stopping) any existing handler for the jobType.
+// the existing handler finishes its in-flight jobs in the background before stopping.
 func (s *scheduler) handle(jobType string, runnable Runnable, options ...Option) {
+	old, _ := s.swap(jobType, false, runnable, options...)
+	s.stopReplaced(jobType, old)
+}
+
+// replace swaps the handler for jobType, returning ErrJobTypeNotHandled if there isn't one
+func (s *scheduler) replace(jobType string, runnable Runnable, options ...Option) error {
+	old, err := s.swap(jobType, true, runnable, options...)
+	if err != nil {
+		return err
+	}
+
+	s.stopReplaced(jobType, old)
+
+	return nil
+}
+
+// stopReplaced stops a worker that has been replaced in the background, allowing its in-flight jobs to complete
+func (s *scheduler) stopReplaced(jobType string, old *worker) {
+	if old == nil {
+		return
+	}
+
+	go func() {
+		if err := old.stop(); err != nil {
+			s.logger.Error(errors.Wrapf(err, "failed to stop replaced %s worker", jobType))
+		}
+	}()
+}
+
+// swap adds a handler and returns the worker it replaced, if any. If mustExist is true and
+// there is no handler for jobType, nothing is added and ErrJobTypeNotHandled is returned.
+func (s *scheduler) swap(jobType string, mustExist bool, runnable Runnable, options ...Option) (*worker, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	old, exists := s.workers[jobType]
+	if mustExist && !exists {
+		return nil, ErrJobTypeNotHandled
+	}
+
 	// apply the provided options
 	opts := defaultOpts(jobType)
 	for _, o := range options {
 		opts = o(opts)
 	}
 
-	w := newWorker(runnable, s.store, s.cache, opts)
+	opts.clock = s.opts.clock
+	opts.synchronous = s.opts.synchronous
+
+	w := newWorker(runnable, s.store, s.namespacedCache(opts), s.resourcePool(opts), s.getMiddleware, opts)
 
 	s.workers[jobType] = w
 
 	if opts.preWarm {
 		go func() {
-			if err := w.start(s.schedule); err != nil {
+			// the worker may have been replaced before it could start, which isn't an error
+			if err := w.start(s.schedule); err != nil && err != ErrWorkerStopped {
 				s.logger.Error(errors.Wrapf(err, "failed to preWarm %s worker", jobType))
 			}
 		}()
 	}
+
+	return old, nil
+}
+
+// unhandle removes the handler for jobType, waiting for it to finish its in-flight jobs and stop
+func (s *scheduler) unhandle(jobType string) error {
+	s.lock.Lock()
+	w, exists := s.workers[jobType]
+	delete(s.workers, jobType)
+	s.lock.Unlock()
+
+	if !exists {
+		return ErrJobTypeNotHandled
+	}
+
+	return w.stop()
+}
+
+// namespacedCache returns the cache namespace that the worker described by opts should use. THIS DOES NOT LOCK. THE CALLER MUST LOCK.
+func (s *scheduler) namespacedCache(opts workerOpts) Cache {
+	namespace := opts.jobType
+	if opts.sharedCache {
+		namespace = CacheNamespaceShared
+	} else if opts.cacheNamespace != "" {
+		namespace = opts.cacheNamespace
+	}
+
+	ns, exists := s.namespaces[namespace]
+	if !exists {
+		ns = newCacheNamespace(s.cache, namespace)
+		s.namespaces[namespace] = ns
+	}
+
+	if opts.cacheMaxKeys > 0 || opts.cacheMaxBytes > 0 {
+		ns.setQuota(opts.cacheMaxKeys, opts.cacheMaxBytes)
+	}
+
+	return ns
+}
+
+// resourcePool returns the resource pool that the worker described by opts is bound to, if any. THIS DOES NOT LOCK. THE CALLER MUST LOCK.
+func (s *scheduler) resourcePool(opts workerOpts) *resourcePool {
+	if opts.resourcePool == "" {
+		return nil
+	}
+
+	size := opts.resourcePoolSize
+	if size < 1 {
+		size = 1
+	}
+
+	pool, exists := s.resources[opts.resourcePool]
+	if !exists {
+		pool = newResourcePool(opts.resourcePool, size)
+		s.resources[opts.resourcePool] = pool
+	} else {
+		pool.setSize(size)
+	}
+
+	return pool
 }
 
 func (s *scheduler) watch(sched Schedule) {
 	s.watcher.watch(sched)
 }
 
+func (s *scheduler) schedules() []ScheduleInfo {
+	return s.watcher.info()
+}
+
 func (s *scheduler) getWorker(jobType string) *worker {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -104,3 +296,65 @@ func (s *scheduler) getWorker(jobType string) *worker {
 
 	return nil
 }
+
+// jobTypes returns the job types with a registered worker, along with each worker's queue depth
+func (s *scheduler) jobTypes() map[string]int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	types := map[string]int{}
+	for jobType, w := range s.workers {
+		types[jobType] = w.queueDepth()
+	}
+
+	return types
+}
+
+// handlers returns info about each registered worker, sorted by job type
+func (s *scheduler) handlers() []HandlerInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	infos := make([]HandlerInfo, 0, len(s.workers))
+	for _, w := range s.workers {
+		infos = append(infos, w.info())
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].JobType < infos[j].JobType
+	})
+
+	return infos
+}
+
+func (s *scheduler) use(middleware ...Middleware) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.middleware = append(s.middleware, middleware...)
+}
+
+func (s *scheduler) getMiddleware() []Middleware {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.middleware
+}
+
+// useRouter sets the router used to forward jobs to peers, returning the one it replaced, if any
+func (s *scheduler) useRouter(router *peerRouter) *peerRouter {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	old := s.router
+	s.router = router
+
+	return old
+}
+
+func (s *scheduler) getRouter() *peerRouter {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.router
+}
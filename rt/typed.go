@@ -0,0 +1,162 @@
+package rt
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/suborbital/grav/grav"
+)
+
+// ErrTypedSignature and others are errors related to typed Runnables
+var (
+	ErrTypedSignature = errors.New("typed function must have signature func(*rt.Ctx, In) (Out, error)")
+	ErrTypedInput     = errors.New("job data does not match the typed function's input")
+)
+
+// RunnableFunc is an adapter that allows an ordinary function to be used as a Runnable
+type RunnableFunc func(Job, *Ctx) (interface{}, error)
+
+// Run calls f(job, ctx)
+func (f RunnableFunc) Run(job Job, ctx *Ctx) (interface{}, error) {
+	return f(job, ctx)
+}
+
+// OnChange does nothing
+func (f RunnableFunc) OnChange(_ ChangeEvent) error {
+	return nil
+}
+
+// Codec encodes and decodes the input and output of typed Runnables
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is the Codec used by Typed
+type JSONCodec struct{}
+
+// Marshal JSON-encodes v
+func (j JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal JSON-decodes data into v
+func (j JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+var (
+	ctxType   = reflect.TypeOf(&Ctx{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// typedRunnable calls a function with a concrete input type using reflection
+type typedRunnable struct {
+	fn     reflect.Value
+	inType reflect.Type
+	codec  Codec
+}
+
+// Typed wraps a function with the signature func(*rt.Ctx, In) (Out, error) as a Runnable, using JSONCodec.
+// Job data is decoded into In, and the function's output is encoded into []byte. See TypedWithCodec.
+func Typed(fn interface{}) (Runnable, error) {
+	return TypedWithCodec(fn, JSONCodec{})
+}
+
+// TypedWithCodec wraps a function with the signature func(*rt.Ctx, In) (Out, error) as a Runnable.
+// If the job data is already an In, it is passed as-is. []byte and string data (or the data of a grav.Message)
+// is decoded into In using the codec, and any other data is re-encoded with the codec before being decoded.
+// The function's output is encoded into []byte using the codec, unless it is nil.
+// If fn does not have the correct signature, an error wrapping ErrTypedSignature is returned.
+func TypedWithCodec(fn interface{}, codec Codec) (Runnable, error) {
+	fnType := reflect.TypeOf(fn)
+	if fnType == nil || fnType.Kind() != reflect.Func {
+		return nil, errors.Wrapf(ErrTypedSignature, "got %v", fnType)
+	}
+
+	if fnType.NumIn() != 2 || fnType.In(0) != ctxType || fnType.NumOut() != 2 || fnType.Out(1) != errorType {
+		return nil, errors.Wrapf(ErrTypedSignature, "got %s", fnType)
+	}
+
+	t := &typedRunnable{
+		fn:     reflect.ValueOf(fn),
+		inType: fnType.In(1),
+		codec:  codec,
+	}
+
+	return t, nil
+}
+
+// Run decodes the job data, calls the function, and encodes its output
+func (t *typedRunnable) Run(job Job, ctx *Ctx) (interface{}, error) {
+	in, err := t.decode(job.data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := t.fn.Call([]reflect.Value{reflect.ValueOf(ctx), in})
+
+	if errVal := out[1]; !errVal.IsNil() {
+		return nil, errVal.Interface().(error)
+	}
+
+	if isNil(out[0]) {
+		return nil, nil
+	}
+
+	result, err := t.codec.Marshal(out[0].Interface())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode typed function output")
+	}
+
+	return result, nil
+}
+
+// OnChange does nothing
+func (t *typedRunnable) OnChange(_ ChangeEvent) error {
+	return nil
+}
+
+// decode converts job data into a value of the function's input type
+func (t *typedRunnable) decode(data interface{}) (reflect.Value, error) {
+	if data != nil && reflect.TypeOf(data) == t.inType {
+		return reflect.ValueOf(data), nil
+	}
+
+	var raw []byte
+
+	switch d := data.(type) {
+	case []byte:
+		raw = d
+	case string:
+		raw = []byte(d)
+	case grav.Message:
+		raw = d.Data()
+	case nil:
+		return reflect.Zero(t.inType), nil
+	default:
+		encoded, err := t.codec.Marshal(d)
+		if err != nil {
+			return reflect.Value{}, errors.Wrapf(ErrTypedInput, "failed to encode job data of type %T: %s", d, err)
+		}
+
+		raw = encoded
+	}
+
+	in := reflect.New(t.inType)
+	if err := t.codec.Unmarshal(raw, in.Interface()); err != nil {
+		return reflect.Value{}, errors.Wrapf(ErrTypedInput, "failed to decode job data into %s: %s", t.inType, err)
+	}
+
+	return in.Elem(), nil
+}
+
+func isNil(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Chan, reflect.Func:
+		return val.IsNil()
+	}
+
+	return false
+}
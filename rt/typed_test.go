@@ -0,0 +1,81 @@
+package rt
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type typedIn struct {
+	Name string `json:"name"`
+}
+
+type typedOut struct {
+	Greeting string `json:"greeting"`
+}
+
+func greet(ctx *Ctx, in typedIn) (*typedOut, error) {
+	if in.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	return &typedOut{Greeting: "hello " + in.Name}, nil
+}
+
+func TestRunnableFunc(t *testing.T) {
+	h := New()
+
+	doFunc := h.Handle("func", RunnableFunc(func(job Job, ctx *Ctx) (interface{}, error) {
+		return "func " + job.String(), nil
+	}))
+
+	res, err := doFunc("job").Then()
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to Then"))
+		return
+	}
+
+	if res.(string) != "func job" {
+		t.Error("expected 'func job', got", res.(string))
+	}
+}
+
+func TestTyped(t *testing.T) {
+	h := New()
+
+	runner, err := Typed(greet)
+	if err != nil {
+		t.Error(errors.Wrap(err, "failed to Typed"))
+		return
+	}
+
+	doGreet := h.Handle("greet", runner)
+
+	for _, data := range []interface{}{[]byte(`{"name":"reactr"}`), `{"name":"reactr"}`, typedIn{Name: "reactr"}, map[string]string{"name": "reactr"}} {
+		res, err := doGreet(data).Then()
+		if err != nil {
+			t.Error(errors.Wrap(err, "failed to Then"))
+			return
+		}
+
+		if string(res.([]byte)) != `{"greeting":"hello reactr"}` {
+			t.Error("unexpected result", string(res.([]byte)))
+		}
+	}
+
+	if _, err := doGreet([]byte(`{}`)).Then(); err == nil {
+		t.Error("expected error from function, did not get one")
+	}
+
+	if _, err := doGreet([]byte(`[1, 2]`)).Then(); errors.Cause(err) != ErrTypedInput {
+		t.Error("expected ErrTypedInput, got", err)
+	}
+}
+
+func TestTypedBadSignature(t *testing.T) {
+	for _, fn := range []interface{}{nil, "not a func", func(in typedIn) typedOut { return typedOut{} }} {
+		if _, err := Typed(fn); errors.Cause(err) != ErrTypedSignature {
+			t.Error("expected ErrTypedSignature, got", err)
+		}
+	}
+}
@@ -1,26 +1,35 @@
 package rt
 
 import (
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// the longest and shortest times that the watcher waits between checking its schedules
+const (
+	maxWatchInterval = time.Second
+	minWatchInterval = time.Millisecond
+)
+
 // watcher holds a set of schedules and "watches"
 // them for new jobs to send to the scheduler
 type watcher struct {
 	schedules    map[string]Schedule
 	scheduleFunc func(Job) *Result
+	clock        Clock
 
 	lock      sync.RWMutex
 	startOnce sync.Once
 }
 
-func newWatcher(scheduleFunc func(Job) *Result) *watcher {
+func newWatcher(scheduleFunc func(Job) *Result, clock Clock) *watcher {
 	w := &watcher{
 		schedules:    map[string]Schedule{},
 		scheduleFunc: scheduleFunc,
+		clock:        clock,
 		lock:         sync.RWMutex{},
 		startOnce:    sync.Once{},
 	}
@@ -32,28 +41,39 @@ func (w *watcher) watch(sched Schedule) {
 	w.lock.Lock()
 	defer w.lock.Unlock()
 
+	// the built-in schedules use the watcher's clock to determine when they are due
+	if clocked, isClocked := sched.(clockedSchedule); isClocked {
+		clocked.useClock(w.clock)
+	}
+
 	w.schedules[uuid.New().String()] = sched
 
 	// we only want to start the ticker if something is actually set up
 	// to be scheduled, so we put it behind a sync.Once
 	w.startOnce.Do(func() {
 		go func() {
-			ticker := time.Tick(time.Second)
-
-			// loop forever and check each schedule for new jobs
-			// repeating every second
+			// loop forever and check each schedule for new jobs, repeating every second
+			// or sooner if a schedule with a shorter interval will next be due before then
 			for {
 				remove := []string{}
+				wait := maxWatchInterval
 
 				w.lock.RLock()
 				for uuid, s := range w.schedules {
 					if s.Done() {
 						// set the schedule to be removed if it's done
 						remove = append(remove, uuid)
-					} else {
-						if job := s.Check(); job != nil {
-							// schedule the job and discard the result
-							w.scheduleFunc(*job).Discard()
+						continue
+					}
+
+					if job := s.Check(); job != nil {
+						// schedule the job and discard the result
+						w.scheduleFunc(*job).Discard()
+					}
+
+					if timed, isTimed := s.(timedSchedule); isTimed && !s.Done() {
+						if until := timed.nextCheck().Sub(w.clock.Now()); until < wait {
+							wait = until
 						}
 					}
 				}
@@ -65,8 +85,53 @@ func (w *watcher) watch(sched Schedule) {
 				}
 				w.lock.Unlock()
 
-				<-ticker
+				if wait < minWatchInterval {
+					wait = minWatchInterval
+				}
+
+				<-w.clock.After(wait)
 			}
 		}()
 	})
 }
+
+// info returns a snapshot of each schedule that is not yet done, sorted by ID
+func (w *watcher) info() []ScheduleInfo {
+	// the write lock is needed because the watch loop checks (and so updates) schedules while holding the read lock
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	infos := []ScheduleInfo{}
+
+	for id, s := range w.schedules {
+		if s.Done() {
+			continue
+		}
+
+		info := ScheduleInfo{
+			ID:   id,
+			Kind: ScheduleKindCustom,
+		}
+
+		switch sched := s.(type) {
+		case *everySchedule:
+			info.Kind = ScheduleKindEvery
+			info.Interval = sched.interval
+		case *afterSchedule:
+			info.Kind = ScheduleKindAfter
+			info.Interval = sched.delay
+		}
+
+		if timed, isTimed := s.(timedSchedule); isTimed {
+			info.NextRun = timed.nextCheck()
+		}
+
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+
+	return infos
+}
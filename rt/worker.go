@@ -2,6 +2,7 @@ package rt
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -16,9 +17,62 @@ const (
 
 // ErrJobTimeout and others are errors related to workers
 var (
-	ErrJobTimeout = errors.New("job timeout")
+	ErrJobTimeout          = errors.New("job timeout")
+	ErrJobDeadlineExceeded = errors.New("job deadline exceeded")
+	ErrWorkerStopped       = errors.New("worker has been stopped")
+	ErrJobTypeNotHandled   = errors.New("no handler registered for jobType")
 )
 
+// HandlerInfo is a snapshot of a registered handler's options and the state of its worker
+type HandlerInfo struct {
+	JobType      string        `json:"jobType"`
+	PoolSize     int           `json:"poolSize"`
+	Timeout      time.Duration `json:"timeout"`
+	PreWarm      bool          `json:"preWarm"`
+	Started      bool          `json:"started"`
+	Threads      int           `json:"threads"`
+	QueueDepth   int           `json:"queueDepth"`
+	ResourcePool string        `json:"resourcePool,omitempty"`
+	Processed    int64         `json:"processed"`
+	Errors       int64         `json:"errors"`
+}
+
+// MarshalJSON encodes the Timeout as a duration string such as "30s", rather than as nanoseconds
+func (h HandlerInfo) MarshalJSON() ([]byte, error) {
+	// the alias type doesn't have this method, so marshalling it doesn't recurse
+	type handlerInfo HandlerInfo
+
+	info := struct {
+		handlerInfo
+		Timeout string `json:"timeout"`
+	}{handlerInfo(h), h.Timeout.String()}
+
+	return json.Marshal(info)
+}
+
+// UnmarshalJSON decodes a HandlerInfo encoded by MarshalJSON
+func (h *HandlerInfo) UnmarshalJSON(data []byte) error {
+	type handlerInfo HandlerInfo
+
+	info := struct {
+		*handlerInfo
+		Timeout string `json:"timeout"`
+	}{handlerInfo: (*handlerInfo)(h)}
+
+	if err := json.Unmarshal(data, &info); err != nil {
+		return err
+	}
+
+	timeout, err := time.ParseDuration(info.Timeout)
+	if err != nil {
+		return errors.Wrap(err, "failed to ParseDuration timeout")
+	}
+
+	h.Timeout = timeout
+
+	return nil
+}
+
 type worker struct {
 	runner   Runnable
 	workChan chan JobReference
@@ -26,43 +80,176 @@ type worker struct {
 	cache    Cache
 	options  workerOpts
 
+	// the resource pool the worker's jobs must acquire a slot from before running, if any
+	resources *resourcePool
+
+	// returns the Reactr-wide middleware, which can change after the worker is created
+	globalMiddleware func() []Middleware
+
 	threads    []*workThread
 	threadLock sync.Mutex
 
+	// when partitioned, each workThread has its own queue for jobs with a partition key
+	partitions []*jobQueue
+
+	// the DoFunc passed to start, used to run jobs synchronously
+	doFunc DoFunc
+
+	// the number of jobs that have been scheduled but not yet picked up by a workThread
+	queued int64
+
+	// the number of jobs that have completed, and the number of those that returned an error
+	processed int64
+	errored   int64
+
+	// inFlight tracks jobs that have been scheduled but not yet completed, so the worker can be drained before stopping
+	inFlight sync.WaitGroup
+	stopping bool
+	stopLock sync.Mutex
+
+	// starting tracks a start that is in progress, so that stop can wait for it before stopping the workThreads
+	starting sync.WaitGroup
+
 	started atomic.Value
 }
 
 // newWorker creates a new goWorker
-func newWorker(runner Runnable, store Storage, cache Cache, opts workerOpts) *worker {
+func newWorker(runner Runnable, store Storage, cache Cache, resources *resourcePool, globalMiddleware func() []Middleware, opts workerOpts) *worker {
 	w := &worker{
-		runner:     runner,
-		workChan:   make(chan JobReference, defaultChanSize),
-		store:      store,
-		cache:      cache,
-		options:    opts,
-		threads:    make([]*workThread, opts.poolSize),
-		threadLock: sync.Mutex{},
-		started:    atomic.Value{},
+		runner:           runner,
+		workChan:         make(chan JobReference, defaultChanSize),
+		store:            store,
+		cache:            cache,
+		options:          opts,
+		resources:        resources,
+		globalMiddleware: globalMiddleware,
+		threads:          make([]*workThread, opts.poolSize),
+		threadLock:       sync.Mutex{},
+		started:          atomic.Value{},
 	}
 
 	w.started.Store(false)
 
+	if opts.partitioned {
+		w.partitions = make([]*jobQueue, opts.poolSize)
+		for i := range w.partitions {
+			w.partitions[i] = newJobQueue()
+		}
+	}
+
 	return w
 }
 
-func (w *worker) schedule(job JobReference) {
+// schedule queues the job for the worker, returning false if the worker is stopping and cannot accept it
+func (w *worker) schedule(job JobReference) bool {
+	w.stopLock.Lock()
+
+	if w.stopping {
+		w.stopLock.Unlock()
+		return false
+	}
+
+	w.inFlight.Add(1)
+	w.stopLock.Unlock()
+
+	if w.options.synchronous {
+		w.runSync(job)
+		return true
+	}
+
+	atomic.AddInt64(&w.queued, 1)
+
+	if w.partitions != nil && job.partitionKey != "" {
+		w.partitions[partitionFor(job.partitionKey, len(w.partitions))].push(job)
+		return true
+	}
+
 	go func() {
 		w.workChan <- job
 	}()
+
+	return true
 }
 
+// isPartitioned returns true if jobs must be scheduled in order
+func (w *worker) isPartitioned() bool {
+	return w.partitions != nil
+}
+
+// unqueue removes and returns the jobs waiting in partitions that have no workThread to run them, such as when the worker fails to start
+func (w *worker) unqueue() []JobReference {
+	w.threadLock.Lock()
+	defer w.threadLock.Unlock()
+
+	jobs := []JobReference{}
+
+	for i, queue := range w.partitions {
+		if w.threads[i] != nil {
+			continue
+		}
+
+		for {
+			job, ok := queue.pop()
+			if !ok {
+				break
+			}
+
+			atomic.AddInt64(&w.queued, -1)
+			w.inFlight.Done()
+
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs
+}
+
+// runSync runs the job on the calling goroutine using one of the worker's workThreads
+func (w *worker) runSync(job JobReference) {
+	defer w.inFlight.Done()
+
+	var wt *workThread
+
+	w.threadLock.Lock()
+	for _, t := range w.threads {
+		if t != nil {
+			wt = t
+			break
+		}
+	}
+	w.threadLock.Unlock()
+
+	if wt == nil {
+		job.result.sendErr(ErrWorkerStopped)
+		return
+	}
+
+	wt.handle(job, w.doFunc)
+}
+
+// start starts the worker's workThreads, returning ErrWorkerStopped if the worker has been stopped
 func (w *worker) start(doFunc DoFunc) error {
+	w.stopLock.Lock()
+
+	// a stopping worker must not start new workThreads, since nothing would ever stop them
+	if w.stopping {
+		w.stopLock.Unlock()
+		return ErrWorkerStopped
+	}
+
 	// this should only be run once per worker, unless startup fails the first time
 	if isStarted := w.started.Load().(bool); isStarted {
+		w.stopLock.Unlock()
 		return nil
 	}
 
 	w.started.Store(true)
+	w.starting.Add(1)
+	w.stopLock.Unlock()
+
+	defer w.starting.Done()
+
+	w.doFunc = doFunc
 
 	started := 0
 	attempts := 0
@@ -70,28 +257,41 @@ func (w *worker) start(doFunc DoFunc) error {
 	for {
 		// fill the "pool" with workThreads
 		for i := started; i < w.options.poolSize; i++ {
-			wt := newWorkThread(w.runner, w.workChan, w.store, w.cache, w.options.jobTimeoutSeconds)
+			wt := newWorkThread(w, w.options.jobTimeout)
+			if w.partitions != nil {
+				wt.partition = w.partitions[i]
+			}
 
 			// give the runner opportunity to provision resources if needed
 			if err := w.runner.OnChange(ChangeTypeStart); err != nil {
-				fmt.Println(errors.Wrapf(err, "Runnable returned OnStart error, will retry in %ds", w.options.retrySecs))
+				fmt.Println(errors.Wrapf(err, "Runnable returned OnStart error, will retry in %s", w.options.retryDelay))
 				break
 			} else {
 				started++
 			}
 
-			wt.run(doFunc)
+			// synchronous workers run jobs on the scheduling goroutine, so their workThreads don't need to wait for work
+			if !w.options.synchronous {
+				wt.run(doFunc)
+			}
 
+			w.threadLock.Lock()
 			w.threads[i] = wt
+			w.threadLock.Unlock()
 		}
 
 		if started == w.options.poolSize {
 			break
 		} else {
 			if attempts >= w.options.numRetries {
-				if started == 0 {
-					// if no threads were able to start, ensure that
+				if started == 0 || w.isPartitioned() {
+					// jobs hashed to a partition without a workThread would never run, so a partitioned
+					// worker must start all of its workThreads or none. Either way, ensure that
 					// the next job causes another attempt
+					if err := w.stopThreads(); err != nil {
+						fmt.Println(errors.Wrap(err, "failed to stopThreads"))
+					}
+
 					w.started.Store(false)
 				}
 
@@ -99,7 +299,12 @@ func (w *worker) start(doFunc DoFunc) error {
 			}
 
 			attempts++
-			<-time.After(time.Duration(time.Second * time.Duration(w.options.retrySecs)))
+			<-time.After(w.options.retryDelay)
+
+			// there's no point retrying for a worker that has been stopped in the meantime
+			if w.isStopping() {
+				return ErrWorkerStopped
+			}
 		}
 	}
 
@@ -110,27 +315,129 @@ func (w *worker) isStarted() bool {
 	return w.started.Load().(bool)
 }
 
+func (w *worker) isStopping() bool {
+	w.stopLock.Lock()
+	defer w.stopLock.Unlock()
+
+	return w.stopping
+}
+
+// stop prevents the worker from accepting new jobs, waits for its queued and running jobs to complete,
+// and then stops each of its workThreads, giving the Runnable the opportunity to release its resources
+func (w *worker) stop() error {
+	w.stopLock.Lock()
+	w.stopping = true
+	w.stopLock.Unlock()
+
+	// a start that is already in progress may still be creating workThreads, so wait for it to finish
+	w.starting.Wait()
+	w.inFlight.Wait()
+
+	err := w.stopThreads()
+
+	if w.options.onStopped != nil {
+		w.options.onStopped()
+	}
+
+	return err
+}
+
+// stopThreads stops each of the worker's workThreads, giving the Runnable the opportunity to release its resources
+func (w *worker) stopThreads() error {
+	w.threadLock.Lock()
+	defer w.threadLock.Unlock()
+
+	var stopErr error
+
+	for i, wt := range w.threads {
+		if wt == nil {
+			continue
+		}
+
+		wt.Stop()
+		w.threads[i] = nil
+
+		if err := w.runner.OnChange(ChangeTypeStop); err != nil && stopErr == nil {
+			stopErr = errors.Wrap(err, "Runnable returned OnChange error")
+		}
+	}
+
+	return stopErr
+}
+
+// info returns a snapshot of the worker's options and state
+func (w *worker) info() HandlerInfo {
+	w.threadLock.Lock()
+	threads := 0
+	for _, wt := range w.threads {
+		if wt != nil {
+			threads++
+		}
+	}
+	w.threadLock.Unlock()
+
+	info := HandlerInfo{
+		JobType:      w.options.jobType,
+		PoolSize:     w.options.poolSize,
+		Timeout:      w.options.jobTimeout,
+		PreWarm:      w.options.preWarm,
+		Started:      w.isStarted(),
+		Threads:      threads,
+		QueueDepth:   w.queueDepth(),
+		ResourcePool: w.options.resourcePool,
+		Processed:    atomic.LoadInt64(&w.processed),
+		Errors:       atomic.LoadInt64(&w.errored),
+	}
+
+	return info
+}
+
+// runFunc returns the Runnable's Run function wrapped with the global and worker-specific middleware
+func (w *worker) runFunc() RunFunc {
+	middleware := []Middleware{}
+	if w.globalMiddleware != nil {
+		middleware = append(middleware, w.globalMiddleware()...)
+	}
+
+	middleware = append(middleware, w.options.middleware...)
+
+	return chain(w.runner.Run, middleware...)
+}
+
+// queueDepth returns the number of jobs waiting to be picked up by a workThread
+func (w *worker) queueDepth() int {
+	return int(atomic.LoadInt64(&w.queued))
+}
+
+// isSaturated returns true if the worker's queue has reached the depth at which jobs should overflow to peers
+func (w *worker) isSaturated() bool {
+	return w.options.overflowDepth > 0 && w.queueDepth() >= w.options.overflowDepth
+}
+
 type workThread struct {
-	runner         Runnable
-	workChan       chan JobReference
-	store          Storage
-	cache          Cache
-	timeoutSeconds int
-	context        context.Context
-	cancelFunc     context.CancelFunc
+	worker     *worker
+	runner     Runnable
+	workChan   chan JobReference
+	partition  *jobQueue
+	store      Storage
+	cache      Cache
+	timeout    time.Duration
+	context    context.Context
+	cancelFunc context.CancelFunc
 }
 
-func newWorkThread(runner Runnable, workChan chan JobReference, store Storage, cache Cache, timeoutSeconds int) *workThread {
+func newWorkThread(w *worker, timeout time.Duration) *workThread {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
 	wt := &workThread{
-		runner:         runner,
-		workChan:       workChan,
-		store:          store,
-		cache:          cache,
-		timeoutSeconds: timeoutSeconds,
-		context:        ctx,
-		cancelFunc:     cancelFunc,
+		worker:     w,
+		runner:     w.runner,
+		workChan:   w.workChan,
+		store:      w.store,
+		cache:      w.cache,
+		timeout:    timeout,
+		context:    ctx,
+		cancelFunc: cancelFunc,
 	}
 
 	return wt
@@ -139,51 +446,130 @@ func newWorkThread(runner Runnable, workChan chan JobReference, store Storage, c
 func (wt *workThread) run(doFunc DoFunc) {
 	go func() {
 		for {
-			// die if the context has been cancelled
-			if wt.context.Err() != nil {
-				break
+			// wait for the next job, or die if the context has been cancelled
+			select {
+			case <-wt.context.Done():
+				return
+			case jobRef := <-wt.workChan:
+				// TODO: check to see if the workThread pool is sufficient, and attempt to fill it if not
+
+				wt.handleQueued(jobRef, doFunc)
+			case <-wt.partitionReady():
+				for {
+					jobRef, ok := wt.partition.pop()
+					if !ok {
+						break
+					}
+
+					wt.handleQueued(jobRef, doFunc)
+				}
 			}
+		}
+	}()
+}
 
-			// wait for the next job
-			jobRef := <-wt.workChan
+// partitionReady returns the channel signalled when the workThread's partition has jobs. If the worker is not
+// partitioned, a nil channel is returned, which is never ready.
+func (wt *workThread) partitionReady() chan struct{} {
+	if wt.partition == nil {
+		return nil
+	}
+
+	return wt.partition.ready
+}
 
-			// TODO: check to see if the workThread pool is sufficient, and attempt to fill it if not
+// handleQueued handles a job that was taken from one of the worker's queues
+func (wt *workThread) handleQueued(jobRef JobReference, doFunc DoFunc) {
+	atomic.AddInt64(&wt.worker.queued, -1)
 
-			// fetch the full job from storage
-			job, err := wt.store.Get(jobRef.uuid)
-			if err != nil {
-				jobRef.result.sendErr(err)
-				continue
-			}
+	wt.handle(jobRef, doFunc)
 
-			ctx := newCtx(wt.cache, doFunc)
+	wt.worker.inFlight.Done()
+}
 
-			var result interface{}
+// handle runs a job and sends its result
+func (wt *workThread) handle(jobRef JobReference, doFunc DoFunc) {
+	// fetch the full job from storage
+	job, err := wt.store.Get(jobRef.uuid)
+	if err != nil {
+		jobRef.result.sendErr(err)
+		return
+	}
 
-			if wt.timeoutSeconds == 0 {
-				result, err = wt.runner.Run(job, ctx)
-			} else {
-				result, err = wt.runWithTimeout(job, ctx)
-			}
+	now := wt.worker.options.clock.Now()
 
-			wt.store.AddResult(job.UUID(), result, err)
+	// the job must complete by the earlier of its deadline and its timeout
+	limit, limitErr := job.deadline, ErrJobDeadlineExceeded
+	if wt.timeout > 0 {
+		timeout := now.Add(wt.timeout)
+		if limit.IsZero() || timeout.Before(limit) {
+			limit, limitErr = timeout, ErrJobTimeout
+		}
+	}
 
-			if err != nil {
-				jobRef.result.sendErr(err)
-				continue
-			}
+	ctx := newCtx(wt.cache, doFunc, job.meta, limit)
+
+	var result interface{}
+
+	run := wt.worker.runFunc()
 
-			jobRef.result.sendResult(result)
+	if !job.deadline.IsZero() && !now.Before(job.deadline) {
+		// don't bother running a job that has already run out of time
+		err = ErrJobDeadlineExceeded
+	} else if run, err = wt.acquireResource(run, limit, limitErr); err == nil {
+		// a job that ran out of time waiting for its resource pool is never run
+		if limit.IsZero() {
+			result, err = run(job, ctx)
+		} else {
+			result, err = wt.runWithTimeout(run, job, ctx, limit.Sub(wt.worker.options.clock.Now()), limitErr)
 		}
-	}()
+	}
+
+	wt.store.AddResult(job.UUID(), result, err)
+
+	atomic.AddInt64(&wt.worker.processed, 1)
+
+	if err != nil {
+		atomic.AddInt64(&wt.worker.errored, 1)
+		jobRef.result.sendErr(err)
+		return
+	}
+
+	jobRef.result.sendResult(result)
+}
+
+// acquireResource takes a slot from the worker's resource pool, if any, before the job runs, returning limitErr if
+// the job's limit passes while waiting. The returned RunFunc releases the slot once the job completes.
+func (wt *workThread) acquireResource(run RunFunc, limit time.Time, limitErr error) (RunFunc, error) {
+	pool := wt.worker.resources
+	if pool == nil {
+		return run, nil
+	}
+
+	var cancel <-chan time.Time
+	if !limit.IsZero() {
+		cancel = wt.worker.options.clock.After(limit.Sub(wt.worker.options.clock.Now()))
+	}
+
+	if !pool.acquire(cancel) {
+		return nil, limitErr
+	}
+
+	if !limit.IsZero() && !wt.worker.options.clock.Now().Before(limit) {
+		pool.release()
+		return nil, limitErr
+	}
+
+	return pool.holding(run), nil
 }
 
-func (wt *workThread) runWithTimeout(job Job, ctx *Ctx) (interface{}, error) {
+// runWithTimeout runs the job, returning timeoutErr if it does not complete within timeout
+func (wt *workThread) runWithTimeout(run RunFunc, job Job, ctx *Ctx, timeout time.Duration, timeoutErr error) (interface{}, error) {
 	resultChan := make(chan interface{})
 	errChan := make(chan error)
 
 	go func() {
-		result, err := wt.runner.Run(job, ctx)
+		result, err := run(job, ctx)
 		if err != nil {
 			errChan <- err
 		} else {
@@ -196,8 +582,8 @@ func (wt *workThread) runWithTimeout(job Job, ctx *Ctx) (interface{}, error) {
 		return result, nil
 	case err := <-errChan:
 		return nil, err
-	case <-time.After(time.Duration(time.Second * time.Duration(wt.timeoutSeconds))):
-		return nil, ErrJobTimeout
+	case <-wt.worker.options.clock.After(timeout):
+		return nil, timeoutErr
 	}
 }
 
@@ -206,22 +592,37 @@ func (wt *workThread) Stop() {
 }
 
 type workerOpts struct {
-	jobType           string
-	poolSize          int
-	jobTimeoutSeconds int
-	numRetries        int
-	retrySecs         int
-	preWarm           bool
+	jobType          string
+	poolSize         int
+	jobTimeout       time.Duration
+	numRetries       int
+	retryDelay       time.Duration
+	preWarm          bool
+	cacheNamespace   string
+	sharedCache      bool
+	cacheMaxKeys     int
+	cacheMaxBytes    int
+	overflowDepth    int
+	partitioned      bool
+	resourcePool     string
+	resourcePoolSize int
+	middleware       []Middleware
+	onStopped        func()
+
+	// set by the scheduler from the Reactr's options
+	clock       Clock
+	synchronous bool
 }
 
 func defaultOpts(jobType string) workerOpts {
 	o := workerOpts{
-		jobType:           jobType,
-		poolSize:          1,
-		jobTimeoutSeconds: 0,
-		retrySecs:         3,
-		numRetries:        5,
-		preWarm:           false,
+		jobType:    jobType,
+		poolSize:   1,
+		jobTimeout: 0,
+		retryDelay: time.Second * 3,
+		numRetries: 5,
+		preWarm:    false,
+		clock:      systemClock{},
 	}
 
 	return o
@@ -1,7 +1,10 @@
 package rt
 
 import (
+	"encoding/json"
 	"log"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -78,3 +81,154 @@ func TestRunnerWithJobTimeout(t *testing.T) {
 		t.Error("job should have timed out, but did not")
 	}
 }
+
+func TestRunnerWithJobTimeoutDuration(t *testing.T) {
+	h := New()
+
+	doTimeout := h.Handle("timeout", timeoutRunner{}, Timeout(time.Millisecond*250))
+
+	start := time.Now()
+
+	if _, err := doTimeout("hello").Then(); err != ErrJobTimeout {
+		t.Error("job should have timed out, but did not")
+	}
+
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("job should have timed out after 250ms, took %s", elapsed)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	h := New()
+
+	doGeneric := h.Handle("generic", generic{}, PoolSize(2), TimeoutSeconds(3))
+	doBad := h.Handle("bad", badRunner{}, MaxRetries(0))
+	h.Handle("unused", generic{})
+
+	if _, err := doGeneric("last").Then(); err != nil {
+		t.Fatal(err)
+	}
+
+	doGeneric("fail").Then()
+
+	doBad(nil).Then()
+
+	handlers := h.Handlers()
+	if len(handlers) != 3 {
+		t.Fatalf("expected 3 handlers, got %d", len(handlers))
+	}
+
+	bad, gen, unused := handlers[0], handlers[1], handlers[2]
+
+	if gen.JobType != "generic" || gen.PoolSize != 2 || gen.Timeout != time.Second*3 {
+		t.Errorf("unexpected options for generic handler: %+v", gen)
+	}
+
+	if !gen.Started || gen.Threads != 2 || gen.Processed != 2 || gen.Errors != 1 {
+		t.Errorf("unexpected state for generic handler: %+v", gen)
+	}
+
+	if bad.JobType != "bad" || bad.Started || bad.Threads != 0 {
+		t.Errorf("unexpected state for bad handler: %+v", bad)
+	}
+
+	if unused.JobType != "unused" || unused.Started || unused.Processed != 0 {
+		t.Errorf("unexpected state for unused handler: %+v", unused)
+	}
+}
+
+// slowStartRunner blocks its first start until release is closed, and counts starts and stops
+type slowStartRunner struct {
+	release chan struct{}
+	starts  int32
+	stops   int32
+}
+
+func (s *slowStartRunner) Run(job Job, ctx *Ctx) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *slowStartRunner) OnChange(change ChangeEvent) error {
+	switch change {
+	case ChangeTypeStart:
+		<-s.release
+		atomic.AddInt32(&s.starts, 1)
+	case ChangeTypeStop:
+		atomic.AddInt32(&s.stops, 1)
+	}
+
+	return nil
+}
+
+func TestStopDuringStart(t *testing.T) {
+	runner := &slowStartRunner{release: make(chan struct{})}
+
+	opts := defaultOpts("slow")
+	opts.poolSize = 2
+
+	w := newWorker(runner, newMemoryStorage(), newMemoryCache(), nil, nil, opts)
+
+	started := make(chan error)
+	go func() {
+		started <- w.start(nil)
+	}()
+
+	// give start a chance to begin before stopping
+	time.Sleep(time.Millisecond * 50)
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- w.stop()
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("stop returned before the start in progress finished")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(runner.release)
+
+	if err := <-started; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+
+	if starts, stops := atomic.LoadInt32(&runner.starts), atomic.LoadInt32(&runner.stops); starts != 2 || stops != 2 {
+		t.Errorf("expected every started workThread to be stopped, got %d starts and %d stops", starts, stops)
+	}
+
+	if info := w.info(); info.Threads != 0 {
+		t.Errorf("expected no threads, got %d", info.Threads)
+	}
+
+	// a stopped worker must never start again
+	if err := w.start(nil); err != ErrWorkerStopped {
+		t.Errorf("expected ErrWorkerStopped, got %v", err)
+	}
+}
+
+func TestHandlerInfoJSON(t *testing.T) {
+	info := HandlerInfo{JobType: "generic", PoolSize: 2, Timeout: time.Second * 30}
+
+	infoJSON, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(errors.Wrap(err, "failed to Marshal"))
+	}
+
+	if !strings.Contains(string(infoJSON), `"timeout":"30s"`) {
+		t.Error("expected timeout as a duration string, got", string(infoJSON))
+	}
+
+	decoded := HandlerInfo{}
+	if err := json.Unmarshal(infoJSON, &decoded); err != nil {
+		t.Fatal(errors.Wrap(err, "failed to Unmarshal"))
+	}
+
+	if decoded != info {
+		t.Errorf("expected %+v, got %+v", info, decoded)
+	}
+}
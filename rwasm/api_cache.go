@@ -1,7 +1,11 @@
 package rwasm
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/pkg/errors"
+	"github.com/suborbital/reactr/rt"
 	"github.com/wasmerio/wasmer-go/wasmer"
 )
 
@@ -83,3 +87,281 @@ func cache_get(keyPointer int32, keySize int32, destPointer int32, destMaxSize i
 
 	return int32(len(valBytes))
 }
+
+func cacheIncrement() *HostFn {
+	fn := func(args ...wasmer.Value) (interface{}, error) {
+		keyPointer := args[0].I32()
+		keySize := args[1].I32()
+		delta := args[2].I32()
+		destPointer := args[3].I32()
+		destMaxSize := args[4].I32()
+		ident := args[5].I32()
+
+		ret := cache_increment(keyPointer, keySize, delta, destPointer, destMaxSize, ident)
+
+		return ret, nil
+	}
+
+	return newHostFn("cache_increment", 6, true, fn)
+}
+
+// cache_increment writes the new value of the counter into dest as a decimal string,
+// since the counter is 64 bits and can be negative it cannot be used as the return value
+func cache_increment(keyPointer int32, keySize int32, delta int32, destPointer int32, destMaxSize int32, identifier int32) int32 {
+	inst, err := instanceForIdentifier(identifier)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "[rwasm] alert: invalid identifier used, potential malicious activity"))
+		return -1
+	}
+
+	atomic, ok := inst.rtCtx.Cache.(rt.AtomicCache)
+	if !ok {
+		logger.ErrorString("[rwasm] cache does not support atomic operations")
+		return -3
+	}
+
+	key := inst.readMemory(keyPointer, keySize)
+
+	logger.Debug("[rwasm] incrementing cache key", string(key))
+
+	val, err := atomic.Increment(string(key), int64(delta))
+	if err != nil {
+		logger.ErrorString("[rwasm] failed to increment cache key", string(key), err.Error())
+		return -2
+	}
+
+	valBytes := []byte(strconv.FormatInt(val, 10))
+
+	if len(valBytes) <= int(destMaxSize) {
+		inst.writeMemoryAtLocation(destPointer, valBytes)
+	}
+
+	return int32(len(valBytes))
+}
+
+func cacheCompareAndSwap() *HostFn {
+	fn := func(args ...wasmer.Value) (interface{}, error) {
+		keyPointer := args[0].I32()
+		keySize := args[1].I32()
+		oldPointer := args[2].I32()
+		oldSize := args[3].I32()
+		newPointer := args[4].I32()
+		newSize := args[5].I32()
+		ttl := args[6].I32()
+		ident := args[7].I32()
+
+		ret := cache_compare_and_swap(keyPointer, keySize, oldPointer, oldSize, newPointer, newSize, ttl, ident)
+
+		return ret, nil
+	}
+
+	return newHostFn("cache_compare_and_swap", 8, true, fn)
+}
+
+// cache_compare_and_swap returns 1 if the value was swapped and 0 if it was not
+func cache_compare_and_swap(keyPointer int32, keySize int32, oldPointer int32, oldSize int32, newPointer int32, newSize int32, ttl int32, identifier int32) int32 {
+	inst, err := instanceForIdentifier(identifier)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "[rwasm] alert: invalid identifier used, potential malicious activity"))
+		return -1
+	}
+
+	atomic, ok := inst.rtCtx.Cache.(rt.AtomicCache)
+	if !ok {
+		logger.ErrorString("[rwasm] cache does not support atomic operations")
+		return -3
+	}
+
+	key := inst.readMemory(keyPointer, keySize)
+	oldVal := inst.readMemory(oldPointer, oldSize)
+	newVal := inst.readMemory(newPointer, newSize)
+
+	logger.Debug("[rwasm] swapping cache key", string(key))
+
+	swapped, err := atomic.CompareAndSwap(string(key), oldVal, newVal, int(ttl))
+	if err != nil {
+		logger.ErrorString("[rwasm] failed to swap cache key", string(key), err.Error())
+		return -2
+	}
+
+	if !swapped {
+		return 0
+	}
+
+	return 1
+}
+
+func cacheSetIfAbsent() *HostFn {
+	fn := func(args ...wasmer.Value) (interface{}, error) {
+		keyPointer := args[0].I32()
+		keySize := args[1].I32()
+		valPointer := args[2].I32()
+		valSize := args[3].I32()
+		ttl := args[4].I32()
+		ident := args[5].I32()
+
+		ret := cache_set_if_absent(keyPointer, keySize, valPointer, valSize, ttl, ident)
+
+		return ret, nil
+	}
+
+	return newHostFn("cache_set_if_absent", 6, true, fn)
+}
+
+// cache_set_if_absent returns 1 if the value was set and 0 if the key already existed
+func cache_set_if_absent(keyPointer int32, keySize int32, valPointer int32, valSize int32, ttl int32, identifier int32) int32 {
+	inst, err := instanceForIdentifier(identifier)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "[rwasm] alert: invalid identifier used, potential malicious activity"))
+		return -1
+	}
+
+	atomic, ok := inst.rtCtx.Cache.(rt.AtomicCache)
+	if !ok {
+		logger.ErrorString("[rwasm] cache does not support atomic operations")
+		return -3
+	}
+
+	key := inst.readMemory(keyPointer, keySize)
+	val := inst.readMemory(valPointer, valSize)
+
+	logger.Debug("[rwasm] setting cache key if absent", string(key))
+
+	set, err := atomic.SetIfAbsent(string(key), val, int(ttl))
+	if err != nil {
+		logger.ErrorString("[rwasm] failed to set cache key", string(key), err.Error())
+		return -2
+	}
+
+	if !set {
+		return 0
+	}
+
+	return 1
+}
+
+func cacheExpire() *HostFn {
+	fn := func(args ...wasmer.Value) (interface{}, error) {
+		keyPointer := args[0].I32()
+		keySize := args[1].I32()
+		ttl := args[2].I32()
+		ident := args[3].I32()
+
+		ret := cache_expire(keyPointer, keySize, ttl, ident)
+
+		return ret, nil
+	}
+
+	return newHostFn("cache_expire", 4, true, fn)
+}
+
+func cache_expire(keyPointer int32, keySize int32, ttl int32, identifier int32) int32 {
+	inst, err := instanceForIdentifier(identifier)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "[rwasm] alert: invalid identifier used, potential malicious activity"))
+		return -1
+	}
+
+	expiring, ok := inst.rtCtx.Cache.(rt.ExpiringCache)
+	if !ok {
+		logger.ErrorString("[rwasm] cache does not support expiry")
+		return -3
+	}
+
+	key := inst.readMemory(keyPointer, keySize)
+
+	logger.Debug("[rwasm] expiring cache key", string(key))
+
+	if err := expiring.Expire(string(key), int(ttl)); err != nil {
+		logger.ErrorString("[rwasm] failed to expire cache key", string(key), err.Error())
+		return -2
+	}
+
+	return 0
+}
+
+func cacheTTL() *HostFn {
+	fn := func(args ...wasmer.Value) (interface{}, error) {
+		keyPointer := args[0].I32()
+		keySize := args[1].I32()
+		ident := args[2].I32()
+
+		ret := cache_ttl(keyPointer, keySize, ident)
+
+		return ret, nil
+	}
+
+	return newHostFn("cache_ttl", 3, true, fn)
+}
+
+// cache_ttl returns the number of seconds until the key expires, or 0 if it does not expire
+func cache_ttl(keyPointer int32, keySize int32, identifier int32) int32 {
+	inst, err := instanceForIdentifier(identifier)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "[rwasm] alert: invalid identifier used, potential malicious activity"))
+		return -1
+	}
+
+	expiring, ok := inst.rtCtx.Cache.(rt.ExpiringCache)
+	if !ok {
+		logger.ErrorString("[rwasm] cache does not support expiry")
+		return -3
+	}
+
+	key := inst.readMemory(keyPointer, keySize)
+
+	ttl, err := expiring.TTL(string(key))
+	if err != nil {
+		logger.ErrorString("[rwasm] failed to get TTL for cache key", string(key), err.Error())
+		return -2
+	}
+
+	return int32(ttl)
+}
+
+func cacheKeys() *HostFn {
+	fn := func(args ...wasmer.Value) (interface{}, error) {
+		prefixPointer := args[0].I32()
+		prefixSize := args[1].I32()
+		destPointer := args[2].I32()
+		destMaxSize := args[3].I32()
+		ident := args[4].I32()
+
+		ret := cache_keys(prefixPointer, prefixSize, destPointer, destMaxSize, ident)
+
+		return ret, nil
+	}
+
+	return newHostFn("cache_keys", 5, true, fn)
+}
+
+// cache_keys writes the keys matching the prefix into dest, seperated by newlines
+func cache_keys(prefixPointer int32, prefixSize int32, destPointer int32, destMaxSize int32, identifier int32) int32 {
+	inst, err := instanceForIdentifier(identifier)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "[rwasm] alert: invalid identifier used, potential malicious activity"))
+		return -1
+	}
+
+	enumerable, ok := inst.rtCtx.Cache.(rt.EnumerableCache)
+	if !ok {
+		logger.ErrorString("[rwasm] cache does not support listing keys")
+		return -3
+	}
+
+	prefix := inst.readMemory(prefixPointer, prefixSize)
+
+	keys, err := enumerable.Keys(string(prefix))
+	if err != nil {
+		logger.ErrorString("[rwasm] failed to list cache keys", string(prefix), err.Error())
+		return -2
+	}
+
+	keysBytes := []byte(strings.Join(keys, "\n"))
+
+	if len(keysBytes) <= int(destMaxSize) {
+		inst.writeMemoryAtLocation(destPointer, keysBytes)
+	}
+
+	return int32(len(keysBytes))
+}
@@ -2,6 +2,7 @@ package rwasm
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -100,6 +101,14 @@ func fetch_url(method int32, urlPointer int32, urlSize int32, bodyPointer int32,
 
 	req.Header = *headers
 
+	// don't allow the request to outlive the job that made it
+	if deadline, hasDeadline := inst.rtCtx.Deadline(); hasDeadline {
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		defer cancel()
+
+		req = req.WithContext(ctx)
+	}
+
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		logger.Error(errors.Wrap(err, "failed to Do request"))
@@ -0,0 +1,83 @@
+package rwasm
+
+import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wasmerio/wasmer-go/wasmer"
+)
+
+func jobMetaGet() *HostFn {
+	fn := func(args ...wasmer.Value) (interface{}, error) {
+		keyPointer := args[0].I32()
+		keySize := args[1].I32()
+		destPointer := args[2].I32()
+		destMaxSize := args[3].I32()
+		ident := args[4].I32()
+
+		ret := job_meta_get(keyPointer, keySize, destPointer, destMaxSize, ident)
+
+		return ret, nil
+	}
+
+	return newHostFn("job_meta_get", 5, true, fn)
+}
+
+func job_meta_get(keyPointer int32, keySize int32, destPointer int32, destMaxSize int32, identifier int32) int32 {
+	inst, err := instanceForIdentifier(identifier)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "[rwasm] alert: invalid identifier used, potential malicious activity"))
+		return -1
+	}
+
+	key := inst.readMemory(keyPointer, keySize)
+
+	val := inst.rtCtx.Meta(string(key))
+	if val == "" {
+		return -3
+	}
+
+	valBytes := []byte(val)
+
+	if len(valBytes) <= int(destMaxSize) {
+		inst.writeMemoryAtLocation(destPointer, valBytes)
+	}
+
+	return int32(len(valBytes))
+}
+
+func jobDeadline() *HostFn {
+	fn := func(args ...wasmer.Value) (interface{}, error) {
+		ident := args[0].I32()
+
+		ret := job_deadline(ident)
+
+		return ret, nil
+	}
+
+	return newHostFn("job_deadline", 1, true, fn)
+}
+
+// job_deadline returns the number of milliseconds remaining before the job's deadline (or timeout), or -3 if it has none
+func job_deadline(identifier int32) int32 {
+	inst, err := instanceForIdentifier(identifier)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "[rwasm] alert: invalid identifier used, potential malicious activity"))
+		return -1
+	}
+
+	deadline, hasDeadline := inst.rtCtx.Deadline()
+	if !hasDeadline {
+		return -3
+	}
+
+	remaining := time.Until(deadline).Milliseconds()
+	if remaining < 0 {
+		return 0
+	} else if remaining > math.MaxInt32 {
+		return math.MaxInt32
+	}
+
+	return int32(remaining)
+}
@@ -132,11 +132,60 @@ func (w *wasmEnvironment) addInstance() error {
 	return nil
 }
 
+// removeInstance removes an instance from the environment's pool, and removes the environment
+// from the shared environments array once it has no instances left so that it can be garbage collected.
+// The environment can be shared by more than one worker, so an instance that is running a job is
+// removed only once that job completes.
+func (w *wasmEnvironment) removeInstance() {
+	for {
+		w.lock.Lock()
+		if len(w.instances) == 0 {
+			w.lock.Unlock()
+			return
+		}
+
+		inst := w.instances[len(w.instances)-1]
+		w.lock.Unlock()
+
+		// wait for the instance to finish whatever it's running, without holding
+		// the environment's lock, as the running job may need it to call back over the FFI
+		inst.lock.Lock()
+
+		w.lock.Lock()
+		removed := false
+
+		// another removal may have happened while waiting, in which case try again
+		if len(w.instances) > 0 && w.instances[len(w.instances)-1] == inst {
+			w.instances = w.instances[:len(w.instances)-1]
+			w.instIndex = 0
+			removed = true
+
+			if len(w.instances) == 0 {
+				envLock.Lock()
+				delete(environments, w.UUID)
+				envLock.Unlock()
+			}
+		}
+
+		w.lock.Unlock()
+		inst.lock.Unlock()
+
+		if removed {
+			return
+		}
+	}
+}
+
 // useInstance provides an instance from the environment's pool to be used
 func (w *wasmEnvironment) useInstance(req *request.CoordinatedRequest, ctx *rt.Ctx, instFunc func(*wasmInstance, int32)) error {
 	w.lock.Lock()
 
-	if w.instIndex == len(w.instances)-1 {
+	if len(w.instances) == 0 {
+		w.lock.Unlock()
+		return errors.New("environment has no instances")
+	}
+
+	if w.instIndex >= len(w.instances)-1 {
 		w.instIndex = 0
 	} else {
 		w.instIndex++
@@ -201,8 +250,16 @@ func (w *wasmEnvironment) internals() (*wasmer.Module, *wasmer.Store, *wasmer.Im
 			fetchURL(),
 			cacheSet(),
 			cacheGet(),
+			cacheIncrement(),
+			cacheCompareAndSwap(),
+			cacheSetIfAbsent(),
+			cacheExpire(),
+			cacheTTL(),
+			cacheKeys(),
 			logMsg(),
 			requestGetField(),
+			jobMetaGet(),
+			jobDeadline(),
 			getStaticFile(),
 		)
 
@@ -251,14 +308,18 @@ func instanceForIdentifier(ident int32) (*wasmInstance, error) {
 	ref := rawRef.(instanceReference)
 
 	envLock.RLock()
-	defer envLock.RUnlock()
-
 	env, exists := environments[ref.EnvUUID]
+	envLock.RUnlock()
+
 	if !exists {
 		return nil, errors.New("environment does not exist")
 	}
 
-	if len(env.instances) <= ref.InstIndex-1 {
+	// removeInstance holds env.lock while taking envLock, so envLock must be released first
+	env.lock.Lock()
+	defer env.lock.Unlock()
+
+	if len(env.instances) <= ref.InstIndex {
 		return nil, errors.New("invalid instance index")
 	}
 
@@ -10,8 +10,13 @@ import (
 	"github.com/suborbital/reactr/rt"
 )
 
+// Handler registers Runnables, such as an rt.Reactr
+type Handler interface {
+	Handle(jobType string, runner rt.Runnable, options ...rt.Option) rt.JobFunc
+}
+
 // HandleBundleAtPath loads a .wasm.zip file into the rt instance
-func HandleBundleAtPath(h *rt.Reactr, path string) error {
+func HandleBundleAtPath(h Handler, path string) error {
 	if !strings.HasSuffix(path, ".wasm.zip") {
 		return fmt.Errorf("cannot load bundle %s, does not have .wasm.zip extension", filepath.Base(path))
 	}
@@ -25,7 +30,7 @@ func HandleBundleAtPath(h *rt.Reactr, path string) error {
 }
 
 // HandleBundle loads a .wasm.zip file into the rt instance
-func HandleBundle(h *rt.Reactr, bundle *bundle.Bundle) error {
+func HandleBundle(h Handler, bundle *bundle.Bundle) error {
 	if err := bundle.Directive.Validate(); err != nil {
 		return errors.Wrap(err, "failed to Validate bundle directive")
 	}
@@ -43,9 +48,10 @@ func HandleBundle(h *rt.Reactr, bundle *bundle.Bundle) error {
 		// multiple bundles with conflicting names get mounted.
 
 		// pre-warm so that Runnables have at least one instance active
-		// when the first request is received.
-		h.Handle(jobName, runner, rt.PreWarm())
-		h.Handle(fqfn, runner, rt.PreWarm())
+		// when the first request is received, and scope the cache to the
+		// bundle so that modules from other bundles cannot access its data.
+		h.Handle(jobName, runner, rt.PreWarm(), rt.CacheNamespace(bundle.Directive.Identifier))
+		h.Handle(fqfn, runner, rt.PreWarm(), rt.CacheNamespace(bundle.Directive.Identifier))
 
 	}
 
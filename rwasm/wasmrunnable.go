@@ -98,12 +98,15 @@ func (w *Runner) Run(job rt.Job, ctx *rt.Ctx) (interface{}, error) {
 	return output, nil
 }
 
-// OnChange evt ChangeEventruns when a worker starts using this Runnable
+// OnChange evt ChangeEventruns when a worker starts or stops using this Runnable
 func (w *Runner) OnChange(evt rt.ChangeEvent) error {
-	if evt == rt.ChangeTypeStart {
+	switch evt {
+	case rt.ChangeTypeStart:
 		if err := w.env.addInstance(); err != nil {
 			return errors.Wrap(err, "failed to addInstance")
 		}
+	case rt.ChangeTypeStop:
+		w.env.removeInstance()
 	}
 
 	return nil